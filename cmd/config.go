@@ -0,0 +1,333 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// configKeys maps the keys of a configuration file to the flags they set.
+// Nested keys are joined with dots. Keys for flags a command does not have
+// are ignored, so one file can serve every command.
+var configKeys = map[string]string{
+	"wordlist":      "wordlist",
+	"threads":       "threads",
+	"scan-threads":  "scan-threads",
+	"scope":         "scope",
+	"resume":        "resume",
+	"hit-stats":     "hit-stats",
+	"patterns":      "pattern",
+	"pattern-words": "pattern-words",
+	"verbose":       "verbose",
+
+	"learn.names":  "learn",
+	"learn.corpus": "learn-corpus",
+
+	"dns.resolvers": "resolvers",
+	"dns.ptr-sweep": "ptr-sweep",
+	"dns.domains":   "domains",
+
+	"output.file":   "output",
+	"output.format": "format",
+	"output.dir":    "output-dir",
+
+	"ports.check":   "check-ports",
+	"ports.threads": "port-threads",
+
+	"vhost.ports": "ports",
+
+	"paths.extract":         "extract-files",
+	"paths.file":            "paths",
+	"paths.threads":         "path-threads",
+	"paths.dump-git":        "dump-git",
+	"paths.git-max-objects": "git-max-objects",
+	"paths.git-max-size":    "git-max-size",
+
+	"secrets.scan":   "scan-secrets",
+	"secrets.reveal": "reveal-secrets",
+
+	"http.timeout":           "http-timeout",
+	"http.connect-timeout":   "connect-timeout",
+	"http.max-host-conns":    "max-host-conns",
+	"http.user-agent":        "user-agent",
+	"http.rotate-user-agent": "rotate-user-agent",
+	"http.headers":           "header",
+	"http.cookies":           "cookies",
+	"http.verify-tls":        "verify-tls",
+	"http.proxy":             "proxy",
+
+	"passive.enabled":       "passive",
+	"passive.sources":       "sources",
+	"passive.config":        "sources-config",
+	"passive.ct-logs":       "ct-log",
+	"passive.ct-checkpoint": "ct-checkpoint",
+	"passive.ct-backfill":   "ct-backfill",
+
+	"enrich.ip-db":           "ip-db",
+	"enrich.providers":       "providers",
+	"enrich.tech-signatures": "tech-signatures",
+	"enrich.skip-cdn":        "skip-cdn",
+}
+
+// commandConfigKeys overrides configKeys for commands whose flags mean
+// something else. The --threads flag of scan is the number of hosts scanned
+// at once, which run calls --scan-threads, so it takes that key instead of
+// the DNS concurrency.
+var commandConfigKeys = map[string]map[string]string{
+	"scan": {
+		"threads":      "",
+		"scan-threads": "threads",
+	},
+}
+
+// configProfiles are named bundles of settings, using the configuration
+// file keys. The file and the command line override them.
+var configProfiles = map[string]map[string][]string{
+	"quick": {
+		"threads":              {"100"},
+		"ports.threads":        {"16"},
+		"paths.extract":        {"false"},
+		"paths.dump-git":       {"false"},
+		"secrets.scan":         {"false"},
+		"http.timeout":         {"3"},
+		"http.connect-timeout": {"2"},
+	},
+	"thorough": {
+		"threads":              {"50"},
+		"dns.ptr-sweep":        {"8"},
+		"passive.enabled":      {"true"},
+		"ports.check":          {"true"},
+		"paths.extract":        {"true"},
+		"paths.dump-git":       {"true"},
+		"secrets.scan":         {"true"},
+		"http.timeout":         {"10"},
+		"http.connect-timeout": {"5"},
+	},
+	"stealth": {
+		"threads":                {"5"},
+		"scan-threads":           {"1"},
+		"ports.threads":          {"1"},
+		"paths.threads":          {"1"},
+		"paths.dump-git":         {"false"},
+		"http.max-host-conns":    {"1"},
+		"http.timeout":           {"15"},
+		"http.rotate-user-agent": {"true"},
+	},
+}
+
+// configSetting is a value read from a configuration file with its position
+type configSetting struct {
+	values []string
+	line   int
+}
+
+// registerConfigFlags adds the --config and --profile flags to the root
+// command and applies them to whichever command runs
+func registerConfigFlags(rootCmd *cobra.Command) {
+	var configFile, profile string
+
+	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "", "", "YAML configuration file, overridden by flags given on the command line")
+	rootCmd.PersistentFlags().StringVarP(&profile, "profile", "", "", fmt.Sprintf("Named settings profile (%s), overridden by the configuration file and flags", strings.Join(profileNames(), ", ")))
+
+	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		if err := applyConfig(cmd.Name(), cmd.Flags(), configFile, profile); err != nil {
+			fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// applyConfig sets the flags not given on the command line from the
+// configuration file, then from the profile
+func applyConfig(command string, flags *pflag.FlagSet, configFile string, profile string) error {
+	settings := make(map[string]configSetting)
+	if configFile != "" {
+		var err error
+		settings, err = loadConfig(configFile)
+		if err != nil {
+			return err
+		}
+
+		if setting, ok := settings["profile"]; ok {
+			if profile == "" {
+				profile = setting.values[0]
+			}
+			delete(settings, "profile")
+		}
+	}
+
+	var profileSettings map[string][]string
+	if profile != "" {
+		var ok bool
+		profileSettings, ok = configProfiles[profile]
+		if !ok {
+			return fmt.Errorf("unknown profile %q (use %s)", profile, strings.Join(profileNames(), ", "))
+		}
+	}
+
+	// Flags given on the command line win over everything else
+	changed := make(map[string]bool)
+	flags.Visit(func(flag *pflag.Flag) {
+		changed[flag.Name] = true
+	})
+
+	for _, key := range sortedKeys(settings) {
+		setting := settings[key]
+		if err := setConfigFlag(flags, changed, configFlagName(command, key), setting.values); err != nil {
+			return fmt.Errorf("%s:%d: %s: %v", configFile, setting.line, key, err)
+		}
+	}
+	for key, values := range profileSettings {
+		if _, ok := settings[key]; ok {
+			continue
+		}
+		if err := setConfigFlag(flags, changed, configFlagName(command, key), values); err != nil {
+			return fmt.Errorf("profile %s: %s: %v", profile, key, err)
+		}
+	}
+
+	return nil
+}
+
+// configFlagName returns the flag a configuration key sets on a command
+func configFlagName(command, key string) string {
+	if name, ok := commandConfigKeys[command][key]; ok {
+		return name
+	}
+	return configKeys[key]
+}
+
+// setConfigFlag sets a flag from a configuration value, unless the command
+// has no such flag or it was given on the command line
+func setConfigFlag(flags *pflag.FlagSet, changed map[string]bool, name string, values []string) error {
+	flag := flags.Lookup(name)
+	if flag == nil || changed[flag.Name] {
+		return nil
+	}
+
+	if slice, ok := flag.Value.(pflag.SliceValue); ok {
+		if err := slice.Replace(values); err != nil {
+			return fmt.Errorf("invalid value %q: %v", strings.Join(values, ", "), err)
+		}
+		flag.Changed = true
+		return nil
+	}
+
+	if len(values) != 1 {
+		return fmt.Errorf("expected a single value, got a list")
+	}
+	if err := flags.Set(flag.Name, values[0]); err != nil {
+		return err
+	}
+	return nil
+}
+
+// loadConfig reads a YAML configuration file into its settings, keyed by
+// their dotted names
+func loadConfig(path string) (map[string]configSetting, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+
+	settings := make(map[string]configSetting)
+	if len(document.Content) == 0 {
+		return settings, nil
+	}
+	if err := readConfigNode(document.Content[0], "", settings); err != nil {
+		return nil, fmt.Errorf("%s:%v", path, err)
+	}
+	return settings, nil
+}
+
+// readConfigNode walks a mapping of the configuration file and collects the
+// settings it holds
+func readConfigNode(node *yaml.Node, prefix string, settings map[string]configSetting) error {
+	if node.Kind != yaml.MappingNode {
+		name := strings.TrimSuffix(prefix, ".")
+		if name == "" {
+			name = "document"
+		}
+		return fmt.Errorf("%d: %s must be a mapping of keys to values", node.Line, name)
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		key := prefix + keyNode.Value
+
+		if _, ok := configKeys[key]; !ok && key != "profile" {
+			if isConfigSection(key) {
+				if err := readConfigNode(valueNode, key+".", settings); err != nil {
+					return err
+				}
+				continue
+			}
+			return fmt.Errorf("%d: unknown key %q", keyNode.Line, key)
+		}
+
+		var values []string
+		switch valueNode.Kind {
+		case yaml.ScalarNode:
+			values = []string{valueNode.Value}
+		case yaml.SequenceNode:
+			for _, item := range valueNode.Content {
+				if item.Kind != yaml.ScalarNode {
+					return fmt.Errorf("%d: %s: list items must be plain values", item.Line, key)
+				}
+				values = append(values, item.Value)
+			}
+		default:
+			return fmt.Errorf("%d: %s: expected a value or a list of values", valueNode.Line, key)
+		}
+		if key == "profile" && len(values) != 1 {
+			return fmt.Errorf("%d: profile: expected a single value", valueNode.Line)
+		}
+
+		settings[key] = configSetting{values: values, line: keyNode.Line}
+	}
+
+	return nil
+}
+
+// isConfigSection reports whether a key is a section holding other keys
+func isConfigSection(key string) bool {
+	for name := range configKeys {
+		if strings.HasPrefix(name, key+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// profileNames returns the sorted names of the built-in profiles
+func profileNames() []string {
+	names := make([]string, 0, len(configProfiles))
+	for name := range configProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sortedKeys returns the keys of the settings in order, so errors are
+// reported consistently
+func sortedKeys(settings map[string]configSetting) []string {
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return settings[keys[i]].line < settings[keys[j]].line
+	})
+	return keys
+}
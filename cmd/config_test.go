@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+// writeConfig writes a configuration file for a test and returns its path
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "sub.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// testFlags returns the flags of a command like run, with DNS and host
+// concurrency, or like scan, where --threads is the host concurrency
+func testFlags(command string) *pflag.FlagSet {
+	flags := pflag.NewFlagSet(command, pflag.ContinueOnError)
+	if command == "scan" {
+		flags.IntP("threads", "c", 10, "")
+	} else {
+		flags.IntP("threads", "c", 50, "")
+		flags.Int("scan-threads", 10, "")
+	}
+	flags.Int("port-threads", 4, "")
+	flags.Int("http-timeout", 5, "")
+	flags.String("output", "", "")
+	flags.StringSlice("resolvers", nil, "")
+	flags.StringArrayP("header", "H", nil, "")
+	return flags
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := writeConfig(t, `# settings
+profile: stealth
+threads: 80
+dns:
+  resolvers: [1.1.1.1, 8.8.8.8:53]
+http:
+  headers:
+    - "X-Test: a, b"
+  timeout: 8
+`)
+
+	settings, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]configSetting{
+		"profile":       {values: []string{"stealth"}, line: 2},
+		"threads":       {values: []string{"80"}, line: 3},
+		"dns.resolvers": {values: []string{"1.1.1.1", "8.8.8.8:53"}, line: 5},
+		"http.headers":  {values: []string{"X-Test: a, b"}, line: 7},
+		"http.timeout":  {values: []string{"8"}, line: 9},
+	}
+	if !reflect.DeepEqual(settings, want) {
+		t.Errorf("loadConfig() = %+v, want %+v", settings, want)
+	}
+
+	settings, err = loadConfig(writeConfig(t, "# nothing yet\n"))
+	if err != nil || len(settings) != 0 {
+		t.Errorf("loadConfig(empty) = %v, %v", settings, err)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"threads: 10\nhttp:\n  timout: 8\n", `:3: unknown key "http.timout"`},
+		{"threads: 10\n\nthreds: 20\n", `:3: unknown key "threds"`},
+		{"dns:\n  - 1.1.1.1\n", ":2: dns must be a mapping of keys to values"},
+		{"- threads\n", ":1: document must be a mapping of keys to values"},
+		{"http:\n  headers:\n    - [a, b]\n", ":3: http.headers: list items must be plain values"},
+		{"threads:\n  dns: 5\n", ":2: threads: expected a value or a list of values"},
+		{"profile: [quick, stealth]\n", ":1: profile: expected a single value"},
+	}
+
+	for _, test := range tests {
+		path := writeConfig(t, test.content)
+		_, err := loadConfig(path)
+		if err == nil || err.Error() != path+test.want {
+			t.Errorf("loadConfig(%q) error = %v, want %s%s", test.content, err, path, test.want)
+		}
+	}
+
+	if _, err := loadConfig(writeConfig(t, "threads: [1\n")); err == nil || !strings.Contains(err.Error(), "invalid config file") {
+		t.Errorf("loadConfig(invalid YAML) error = %v", err)
+	}
+	if _, err := loadConfig(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("loadConfig() accepted a missing file")
+	}
+}
+
+func TestApplyConfigOrder(t *testing.T) {
+	path := writeConfig(t, "profile: quick\nhttp:\n  timeout: 8\nports:\n  threads: 2\n")
+
+	// The command line wins over the file, which wins over the profile
+	flags := testFlags("run")
+	if err := flags.Parse([]string{"--port-threads", "6"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyConfig("run", flags, path, ""); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"port-threads": "6",
+		"http-timeout": "8",
+		"threads":      "100",
+		"scan-threads": "10",
+	} {
+		if got := flags.Lookup(name).Value.String(); got != want {
+			t.Errorf("--%s = %s, want %s", name, got, want)
+		}
+	}
+
+	// A profile given on the command line replaces the one in the file
+	flags = testFlags("run")
+	if err := applyConfig("run", flags, path, "stealth"); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"port-threads": "2",
+		"http-timeout": "8",
+		"threads":      "5",
+		"scan-threads": "1",
+	} {
+		if got := flags.Lookup(name).Value.String(); got != want {
+			t.Errorf("stealth: --%s = %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestApplyConfigScanThreads(t *testing.T) {
+	// On scan --threads is the number of hosts at once, set by scan-threads
+	flags := testFlags("scan")
+	if err := applyConfig("scan", flags, "", "stealth"); err != nil {
+		t.Fatal(err)
+	}
+	if got := flags.Lookup("threads").Value.String(); got != "1" {
+		t.Errorf("stealth: --threads = %s, want 1", got)
+	}
+
+	flags = testFlags("scan")
+	if err := applyConfig("scan", flags, "", "quick"); err != nil {
+		t.Fatal(err)
+	}
+	if got := flags.Lookup("threads").Value.String(); got != "10" {
+		t.Errorf("quick: --threads = %s, want the default 10", got)
+	}
+
+	flags = testFlags("scan")
+	path := writeConfig(t, "threads: 200\nscan-threads: 3\n")
+	if err := applyConfig("scan", flags, path, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := flags.Lookup("threads").Value.String(); got != "3" {
+		t.Errorf("file: --threads = %s, want 3", got)
+	}
+}
+
+func TestApplyConfigSlices(t *testing.T) {
+	path := writeConfig(t, "dns:\n  resolvers: [1.1.1.1, 8.8.8.8]\nhttp:\n  headers:\n    - \"X-Test: a, b\"\n    - \"X-Other: c\"\n")
+
+	flags := testFlags("run")
+	if err := applyConfig("run", flags, path, ""); err != nil {
+		t.Fatal(err)
+	}
+	resolvers, _ := flags.GetStringSlice("resolvers")
+	if !reflect.DeepEqual(resolvers, []string{"1.1.1.1", "8.8.8.8"}) {
+		t.Errorf("--resolvers = %v", resolvers)
+	}
+	headers, _ := flags.GetStringArray("header")
+	if !reflect.DeepEqual(headers, []string{"X-Test: a, b", "X-Other: c"}) {
+		t.Errorf("--header = %v", headers)
+	}
+
+	// A list on the command line replaces the one in the file
+	flags = testFlags("run")
+	if err := flags.Parse([]string{"--resolvers", "9.9.9.9"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyConfig("run", flags, path, ""); err != nil {
+		t.Fatal(err)
+	}
+	resolvers, _ = flags.GetStringSlice("resolvers")
+	if !reflect.DeepEqual(resolvers, []string{"9.9.9.9"}) {
+		t.Errorf("--resolvers = %v, want the command line value", resolvers)
+	}
+}
+
+func TestApplyConfigErrors(t *testing.T) {
+	err := applyConfig("run", testFlags("run"), "", "fast")
+	if err == nil || err.Error() != `unknown profile "fast" (use quick, stealth, thorough)` {
+		t.Errorf("unknown profile error = %v", err)
+	}
+
+	path := writeConfig(t, "profile: fast\n")
+	if err := applyConfig("run", testFlags("run"), path, ""); err == nil || !strings.Contains(err.Error(), `unknown profile "fast"`) {
+		t.Errorf("unknown profile in file error = %v", err)
+	}
+
+	path = writeConfig(t, "threads: 10\nhttp:\n  timeout: soon\n")
+	if err := applyConfig("run", testFlags("run"), path, ""); err == nil || !strings.HasPrefix(err.Error(), path+":3: http.timeout: ") {
+		t.Errorf("invalid value error = %v", err)
+	}
+
+	path = writeConfig(t, "output:\n  file: [a.txt, b.txt]\n")
+	if err := applyConfig("run", testFlags("run"), path, ""); err == nil || err.Error() != path+":2: output.file: expected a single value, got a list" {
+		t.Errorf("list for a single value error = %v", err)
+	}
+}
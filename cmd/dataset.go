@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/SayerLinux/sub/pkg/scanner"
+	"github.com/SayerLinux/sub/pkg/utils"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// sourceDataset tags results imported from DNS datasets
+const sourceDataset = "dataset"
+
+// NewImportDatasetCmd creates the import-dataset command, which extracts the
+// subdomains of a target from bulk DNS datasets on disk
+func NewImportDatasetCmd() *cobra.Command {
+	var (
+		domain        string
+		datasetFormat string
+		resolve       bool
+		threads       int
+		outputFile    string
+		format        string
+		verbose       bool
+		scope         string
+		resolvers     []string
+	)
+
+	importCmd := &cobra.Command{
+		Use:   "import-dataset [flags] FILE...",
+		Short: "Extract subdomains from forward-DNS dumps and zone files",
+		Long: `Import-dataset streams forward-DNS JSON dumps and BIND zone files, gzipped or
+not, and extracts every name under the target domain. The names can be
+re-resolved through the scanner before they are reported.`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if domain == "" {
+				fmt.Println("\033[1;31m[!] Error: Target domain is required\033[0m")
+				cmd.Help()
+				os.Exit(1)
+			}
+
+			if format != "csv" && format != "json" {
+				fmt.Printf("\033[1;31m[!] Error: Unknown output format %q (use csv or json)\033[0m\n", format)
+				os.Exit(1)
+			}
+
+			if scope != "" {
+				if err := scanner.LoadScope(scope); err != nil {
+					fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
+					os.Exit(1)
+				}
+			}
+
+			if err := utils.LoadResolvers(resolvers...); err != nil {
+				fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
+				os.Exit(1)
+			}
+
+			if datasetFormat == "auto" {
+				datasetFormat = ""
+			}
+
+			// Names are deduplicated across files, keeping the first address seen
+			var results []scanner.ScanResult
+			index := make(map[string]int)
+			for _, path := range args {
+				fmt.Printf("\033[1;34m[*] Reading dataset: %s\033[0m\n", path)
+				err := scanner.ReadDataset(path, datasetFormat, domain, func(record scanner.DatasetRecord) {
+					if i, ok := index[record.Name]; ok {
+						if results[i].IP == "" {
+							results[i].IP = record.IP
+						}
+						return
+					}
+					index[record.Name] = len(results)
+					results = append(results, scanner.ScanResult{
+						Subdomain: record.Name,
+						IP:        record.IP,
+						Found:     true,
+						Sources:   []string{sourceDataset},
+					})
+				})
+				if err != nil {
+					fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
+					os.Exit(1)
+				}
+			}
+			fmt.Printf("\033[1;34m[*] Found %d names under %s\033[0m\n", len(results), domain)
+
+			if resolve {
+				s := scanner.NewScanner(scanner.Config{
+					Target:       domain,
+					Threads:      threads,
+					OutputFile:   outputFile,
+					OutputFormat: format,
+					Verbose:      verbose,
+				})
+				for _, result := range results {
+					s.AddCandidate(result.Subdomain, sourceDataset)
+				}
+				s.Start()
+				return
+			}
+
+			green := color.New(color.FgGreen).SprintFunc()
+			for _, result := range results {
+				if result.IP != "" {
+					fmt.Printf("%s %s -> %s\n", green("[+]"), result.Subdomain, result.IP)
+				} else {
+					fmt.Printf("%s %s\n", green("[+]"), result.Subdomain)
+				}
+			}
+
+			if outputFile != "" {
+				file, err := os.Create(outputFile)
+				if err != nil {
+					fmt.Printf("\033[1;31m[!] Error: Failed to create output file: %v\033[0m\n", err)
+					os.Exit(1)
+				}
+				defer file.Close()
+
+				if err := scanner.WriteResults(file, domain, format, results); err != nil {
+					fmt.Printf("\033[1;31m[!] Error: Failed to write output file: %v\033[0m\n", err)
+					os.Exit(1)
+				}
+				fmt.Printf("\033[1;32m[+] Results saved to %s\033[0m\n", outputFile)
+			}
+		},
+	}
+
+	// Add flags
+	importCmd.Flags().StringVarP(&domain, "domain", "d", "", "Target domain to extract (required)")
+	importCmd.Flags().StringVarP(&datasetFormat, "dataset-format", "", "auto", "Dataset format (auto, fdns or zone)")
+	importCmd.Flags().BoolVarP(&resolve, "resolve", "r", false, "Re-resolve the imported names and keep only those that exist")
+	importCmd.Flags().StringVarP(&scope, "scope", "", "", "File of domain patterns, IPs and CIDRs that may be contacted, with ! exclusions")
+	importCmd.Flags().StringSliceVarP(&resolvers, "resolvers", "", nil, "DNS resolver IPs (optionally ip:port) or a file of them, used in turn instead of the system resolver")
+	importCmd.Flags().IntVarP(&threads, "threads", "c", 50, "Number of concurrent threads when resolving")
+	importCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output file to save results")
+	importCmd.Flags().StringVarP(&format, "format", "f", "csv", "Output file format (csv or json)")
+	importCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
+
+	return importCmd
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/SayerLinux/sub/pkg/scanner"
+	"github.com/SayerLinux/sub/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// httpFlags holds the settings of the HTTP engine shared by every command
+// sending requests to targets
+type httpFlags struct {
+	timeout         int
+	connectTimeout  int
+	maxHostConns    int
+	userAgents      []string
+	rotateUserAgent bool
+	headers         []string
+	cookieFile      string
+	verifyTLS       bool
+}
+
+// register adds the HTTP engine flags to a command
+func (f *httpFlags) register(cmd *cobra.Command) {
+	defaults := scanner.DefaultHTTPConfig()
+	cmd.Flags().IntVarP(&f.timeout, "http-timeout", "", int(defaults.Timeout/time.Second), "Timeout in seconds for each HTTP request")
+	cmd.Flags().IntVarP(&f.connectTimeout, "connect-timeout", "", int(defaults.ConnectTimeout/time.Second), "Timeout in seconds for connecting and the TLS handshake")
+	cmd.Flags().IntVarP(&f.maxHostConns, "max-host-conns", "", defaults.MaxConnsPerHost, "Maximum number of HTTP connections open to a single host")
+	cmd.Flags().StringArrayVarP(&f.userAgents, "user-agent", "", nil, fmt.Sprintf("User agent or profile (%s) sent with every HTTP request (repeatable)", strings.Join(utils.UserAgentProfileNames(), ", ")))
+	cmd.Flags().BoolVarP(&f.rotateUserAgent, "rotate-user-agent", "", false, "Rotate the given user agents per request, or all browser profiles if fewer than two are given")
+	cmd.Flags().StringArrayVarP(&f.headers, "header", "H", nil, "Header sent with every HTTP request, as \"Name: value\" (repeatable)")
+	cmd.Flags().StringVarP(&f.cookieFile, "cookies", "", "", "Cookie file in the Netscape cookies.txt format sent with matching requests")
+	cmd.Flags().BoolVarP(&f.verifyTLS, "verify-tls", "", false, "Verify TLS certificates of the targets")
+}
+
+// configure applies the flags to the shared HTTP engine
+func (f *httpFlags) configure() error {
+	var userAgents []string
+	for _, value := range f.userAgents {
+		userAgents = append(userAgents, utils.ResolveUserAgent(value))
+	}
+	if f.rotateUserAgent && len(userAgents) < 2 {
+		userAgents = utils.BrowserUserAgents()
+	} else if !f.rotateUserAgent && len(userAgents) > 1 {
+		userAgents = userAgents[len(userAgents)-1:]
+	}
+
+	headers := make(http.Header)
+	for _, header := range f.headers {
+		name, value, ok := strings.Cut(header, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return fmt.Errorf("invalid header %q (use \"Name: value\")", header)
+		}
+		headers.Add(name, strings.TrimSpace(value))
+	}
+
+	var cookies http.CookieJar
+	if f.cookieFile != "" {
+		jar, err := scanner.LoadCookieJar(f.cookieFile)
+		if err != nil {
+			return err
+		}
+		cookies = jar
+	}
+
+	scanner.ConfigureHTTP(scanner.HTTPConfig{
+		Timeout:         time.Duration(f.timeout) * time.Second,
+		ConnectTimeout:  time.Duration(f.connectTimeout) * time.Second,
+		MaxConnsPerHost: f.maxHostConns,
+		UserAgents:      userAgents,
+		Headers:         headers,
+		Cookies:         cookies,
+		VerifyTLS:       f.verifyTLS,
+	})
+	return nil
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/SayerLinux/sub/pkg/scanner"
+	"github.com/spf13/cobra"
+)
+
+// passiveFlags holds the flags shared by commands that can seed the brute
+// force with names from passive sources
+type passiveFlags struct {
+	enabled       bool
+	sources       []string
+	sourcesConfig string
+	ctLogs        []string
+	ctCheckpoint  string
+	ctBackfill    int64
+}
+
+// register adds the passive source flags to a command
+func (f *passiveFlags) register(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&f.enabled, "passive", "", false, "Query passive sources for subdomains and resolve them with the wordlist")
+	cmd.Flags().StringSliceVarP(&f.sources, "sources", "", nil, "Passive sources to query ("+strings.Join(scanner.SourceNames(), ", ")+"), all by default")
+	cmd.Flags().StringVarP(&f.sourcesConfig, "sources-config", "", scanner.DefaultSourcesConfigPath(), "JSON file with passive source API keys and base URLs")
+	cmd.Flags().StringSliceVarP(&f.ctLogs, "ct-log", "", nil, "Certificate transparency log URL to read new entries from (repeatable)")
+	cmd.Flags().StringVarP(&f.ctCheckpoint, "ct-checkpoint", "", scanner.DefaultCTCheckpointPath(), "File recording the position read in each certificate transparency log")
+	cmd.Flags().Int64VarP(&f.ctBackfill, "ct-backfill", "", scanner.DefaultCTBackfill, "Number of past entries to read from a log on its first run")
+}
+
+// addCandidates queries the passive sources and certificate transparency
+// logs for each target and queues every name they return on the scanner.
+// The sources and the log checkpoint are loaded once and shared by all
+// targets. Failing sources are reported and skipped.
+func (f *passiveFlags) addCandidates(s *scanner.Scanner, targets ...string) error {
+	if !f.enabled && len(f.ctLogs) == 0 {
+		return nil
+	}
+
+	sources, err := f.loadSources()
+	if err != nil {
+		return err
+	}
+
+	for _, target := range targets {
+		if len(targets) > 1 {
+			fmt.Printf("\033[1;34m[*] Querying %d passive sources for %s\033[0m\n", len(sources), target)
+		} else {
+			fmt.Printf("\033[1;34m[*] Querying %d passive sources\033[0m\n", len(sources))
+		}
+		results, errs := scanner.EnumerateSources(context.Background(), target, sources)
+		for _, err := range errs {
+			fmt.Printf("\033[1;33m[!] Warning: Passive source failed: %v\033[0m\n", err)
+		}
+
+		for _, result := range results {
+			s.AddCandidate(result.Subdomain, result.Sources...)
+		}
+		fmt.Printf("\033[1;34m[*] Passive sources returned %d names\033[0m\n", len(results))
+	}
+
+	return nil
+}
+
+// loadSources creates the enabled passive sources and a reader for each
+// certificate transparency log, sharing one checkpoint
+func (f *passiveFlags) loadSources() ([]scanner.Source, error) {
+	var sources []scanner.Source
+	if f.enabled {
+		config, err := scanner.LoadSourcesConfig(f.sourcesConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		sources, err = scanner.NewSources(f.sources, config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(f.ctLogs) > 0 {
+		checkpoint, err := scanner.LoadCTCheckpoint(f.ctCheckpoint)
+		if err != nil {
+			return nil, err
+		}
+		for _, logURL := range f.ctLogs {
+			sources = append(sources, &scanner.CTLogSource{
+				Client:     scanner.NewCTClient(logURL, nil),
+				Checkpoint: checkpoint,
+				Backfill:   f.ctBackfill,
+			})
+		}
+	}
+
+	return sources, nil
+}
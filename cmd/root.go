@@ -2,23 +2,46 @@ package cmd
 
 import (
 	"fmt"
+	"net"
 	"os"
+	"strings"
 
 	"github.com/SayerLinux/sub/pkg/scanner"
+	"github.com/SayerLinux/sub/pkg/utils"
 	"github.com/spf13/cobra"
 )
 
 // NewRootCmd creates the root command for the application
 func NewRootCmd() *cobra.Command {
-	// Add scan command
+	// Add scan and run commands
 	scanCmd := NewScanCmd()
+	runCmd := NewRunCmd()
+	importCmd := NewImportDatasetCmd()
+	vhostCmd := NewVhostCmd()
+	wordlistCmd := NewWordlistCmd()
 	var (
-		target      string
+		targets     []string
+		listFile    string
+		resumeFile  string
+		hitStats    string
+		learn       int
+		learnCorpus string
 		wordlist    string
+		patterns    []string
+		wordSets    []string
 		threads     int
 		outputFile  string
+		format      string
 		verbose     bool
 		showVersion bool
+		ptrSweep    int
+		domains     []string
+		ipDatabases []string
+		providers   string
+		scope       string
+		proxies     []string
+		resolvers   []string
+		passive     passiveFlags
 	)
 
 	rootCmd := &cobra.Command{
@@ -33,42 +56,200 @@ func NewRootCmd() *cobra.Command {
 				return
 			}
 
-			if target == "" {
+			var err error
+			targets, err = loadRootTargets(targets, listFile)
+			if err != nil {
+				fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
+				os.Exit(1)
+			}
+			if len(targets) == 0 {
 				fmt.Println("\033[1;31m[!] Error: Target domain is required\033[0m")
 				cmd.Help()
 				os.Exit(1)
 			}
 
-			if wordlist == "" {
-				fmt.Println("\033[1;33m[!] Warning: No wordlist specified, using default wordlist\033[0m")
-				wordlist = "./wordlists/default.txt"
+			if format != "csv" && format != "json" {
+				fmt.Printf("\033[1;31m[!] Error: Unknown output format %q (use csv or json)\033[0m\n", format)
+				os.Exit(1)
+			}
+
+			// CIDR targets are swept with PTR lookups and need no wordlist
+			if _, _, err := net.ParseCIDR(targets[0]); err == nil && len(targets) == 1 {
+				wordlist = ""
+			} else if wordlist == "" && len(patterns) == 0 {
+				fmt.Printf("\033[1;33m[!] Warning: No wordlist specified, using the built-in %s wordlist\033[0m\n", scanner.DefaultWordlist)
+				wordlist = scanner.DefaultWordlist
+			}
+
+			generators, err := parsePatterns(patterns, wordSets)
+			if err != nil {
+				fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
+				os.Exit(1)
+			}
+
+			// Load the databases used to enrich resolved IPs
+			var ipDatabase *scanner.IPDatabase
+			if len(ipDatabases) > 0 {
+				var err error
+				ipDatabase, err = scanner.LoadIPDatabase(ipDatabases...)
+				if err != nil {
+					fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
+					os.Exit(1)
+				}
+				defer ipDatabase.Close()
+			}
+
+			if providers != "" {
+				if err := scanner.LoadProviderSignatures(providers); err != nil {
+					fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
+					os.Exit(1)
+				}
+			}
+
+			if scope != "" {
+				if err := scanner.LoadScope(scope); err != nil {
+					fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
+					os.Exit(1)
+				}
+			}
+
+			if err := loadProxies(proxies); err != nil {
+				fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
+				os.Exit(1)
+			}
+
+			if err := utils.LoadResolvers(resolvers...); err != nil {
+				fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
+				os.Exit(1)
 			}
 
 			// Create scanner configuration
 			config := scanner.Config{
-				Target:     target,
-				Wordlist:   wordlist,
-				Threads:    threads,
-				OutputFile: outputFile,
-				Verbose:    verbose,
+				Targets:      targets,
+				Wordlist:     wordlist,
+				Patterns:     generators,
+				Threads:      threads,
+				OutputFile:   outputFile,
+				OutputFormat: format,
+				Verbose:      verbose,
+				PTRSweep:     ptrSweep,
+				Domains:      domains,
+				IPDatabase:   ipDatabase,
+				ResumeFile:   resumeFile,
+				Learn:        learn,
+				LearnCorpus:  learnCorpus,
+				HitStats:     hitStats,
 			}
 
 			// Start scanning
 			scanner := scanner.NewScanner(config)
+			if err := passive.addCandidates(scanner, targets...); err != nil {
+				fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
+				os.Exit(1)
+			}
 			scanner.Start()
 		},
 	}
 
 	// Add flags
-	rootCmd.Flags().StringVarP(&target, "target", "t", "", "Target domain to scan (required)")
-	rootCmd.Flags().StringVarP(&wordlist, "wordlist", "w", "", "Path to wordlist file")
+	rootCmd.Flags().StringSliceVarP(&targets, "target", "t", nil, "Target domain or CIDR range to scan (repeatable for several domains)")
+	rootCmd.Flags().StringVarP(&listFile, "list", "l", "", "File of target domains to brute force in one run, one per line")
+	rootCmd.Flags().StringVarP(&wordlist, "wordlist", "w", "", "Wordlist file, plain or gzip compressed, or built-in list (small, medium, large)")
+	rootCmd.Flags().StringArrayVarP(&patterns, "pattern", "", nil, "Pattern generating words, with masks (?l ?d ?h ?a), sets ([a-z]), ranges ({01..99}), lists ({dev,prod}) and word sets ({name}) (repeatable)")
+	rootCmd.Flags().StringArrayVarP(&wordSets, "pattern-words", "", nil, "Word set used in patterns as {name}, given as name=wordlist (repeatable)")
+	rootCmd.Flags().IntVarP(&learn, "learn", "", 0, "Resolve the N most probable unseen names of an n-gram model trained on the names found for each domain")
+	rootCmd.Flags().StringVarP(&learnCorpus, "learn-corpus", "", "", "Wordlist of names from earlier scans the n-gram model is also trained on")
+	rootCmd.Flags().StringVarP(&resumeFile, "resume", "", "", "File saving the wordlist position, to continue an interrupted brute force where it stopped")
+	rootCmd.Flags().StringVarP(&hitStats, "hit-stats", "", scanner.DefaultHitStatsPath(), "File recording which wordlist entries resolved, used by wordlist optimise (empty to disable)")
 	rootCmd.Flags().IntVarP(&threads, "threads", "c", 50, "Number of concurrent threads")
 	rootCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output file to save results")
+	rootCmd.Flags().StringVarP(&format, "format", "f", "csv", "Output file format (csv or json)")
 	rootCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
 	rootCmd.Flags().BoolVarP(&showVersion, "version", "", false, "Show version information")
+	rootCmd.Flags().IntVarP(&ptrSweep, "ptr-sweep", "", 0, "Look up the PTR names of N addresses on each side of every IP found")
+	rootCmd.Flags().StringSliceVarP(&domains, "domains", "", nil, "Domains PTR names must be under to be reported (default: the target domain, any name for CIDR targets)")
+	rootCmd.Flags().StringSliceVarP(&ipDatabases, "ip-db", "", nil, "iptoasn TSV or MMDB file used to add ASN, organisation and country to IPs (repeatable)")
+	rootCmd.Flags().StringVarP(&scope, "scope", "", "", "File of domain patterns, IPs and CIDRs that may be contacted, with ! exclusions")
+	rootCmd.Flags().StringSliceVarP(&resolvers, "resolvers", "", nil, "DNS resolver IPs (optionally ip:port) or a file of them, used in turn instead of the system resolver")
+	rootCmd.Flags().StringSliceVarP(&proxies, "proxy", "", nil, "HTTP or SOCKS5 proxy URL (e.g. http://127.0.0.1:8080 for Burp) or file of proxies, rotated per connection (repeatable)")
+	rootCmd.Flags().StringVarP(&providers, "providers", "", "", "JSON file with cloud, CDN and WAF signatures to use instead of the built-in ones")
+	passive.register(rootCmd)
+	registerConfigFlags(rootCmd)
 
 	// Add subcommands
 	rootCmd.AddCommand(scanCmd)
+	rootCmd.AddCommand(runCmd)
+	rootCmd.AddCommand(importCmd)
+	rootCmd.AddCommand(vhostCmd)
+	rootCmd.AddCommand(wordlistCmd)
 
 	return rootCmd
-}
\ No newline at end of file
+}
+
+// loadProxies routes web traffic and port checks through the given proxies
+func loadProxies(proxies []string) error {
+	if len(proxies) == 0 {
+		return nil
+	}
+	if err := utils.LoadProxies(proxies...); err != nil {
+		return err
+	}
+	fmt.Printf("\033[1;34m[*] Using proxies: %s\033[0m\n", utils.ProxyDescription())
+	return nil
+}
+
+// loadRootTargets combines the target flags with the domains of a list file,
+// lowercased and without duplicates. CIDR ranges are swept on their own and
+// cannot be combined with other targets.
+func loadRootTargets(targets []string, listFile string) ([]string, error) {
+	if listFile != "" {
+		domains, err := utils.LoadWordlist(listFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read target list: %v", err)
+		}
+		targets = append(targets, domains...)
+	}
+
+	var unique []string
+	seen := make(map[string]bool)
+	for _, target := range targets {
+		target = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(target), "."))
+		if target == "" || seen[target] {
+			continue
+		}
+		seen[target] = true
+		unique = append(unique, target)
+	}
+
+	if len(unique) > 1 {
+		for _, target := range unique {
+			if _, _, err := net.ParseCIDR(target); err == nil {
+				return nil, fmt.Errorf("CIDR range %s cannot be combined with other targets", target)
+			}
+		}
+	}
+	return unique, nil
+}
+
+// parsePatterns parses the pattern flags, with the word sets given as
+// name=wordlist
+func parsePatterns(patterns []string, wordSets []string) ([]*scanner.Pattern, error) {
+	sets := make(map[string]string)
+	for _, wordSet := range wordSets {
+		name, wordlist, ok := strings.Cut(wordSet, "=")
+		if !ok || name == "" || wordlist == "" {
+			return nil, fmt.Errorf("invalid word set %q (use name=wordlist)", wordSet)
+		}
+		sets[name] = wordlist
+	}
+
+	var generators []*scanner.Pattern
+	for _, pattern := range patterns {
+		generator, err := scanner.ParsePattern(pattern, sets)
+		if err != nil {
+			return nil, err
+		}
+		generators = append(generators, generator)
+	}
+	return generators, nil
+}
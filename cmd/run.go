@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/SayerLinux/sub/pkg/scanner"
+	"github.com/SayerLinux/sub/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// NewRunCmd creates the run command, which chains brute force, service
+// scanning and file extraction in a single streaming pipeline
+func NewRunCmd() *cobra.Command {
+	var (
+		target      string
+		wordlist    string
+		hitStats    string
+		threads     int
+		scanThreads int
+		outputFile  string
+		format      string
+		verbose     bool
+		flags       serviceFlags
+		passive     passiveFlags
+	)
+
+	runCmd := &cobra.Command{
+		Use:   "run",
+		Short: "Discover subdomains, scan their services and extract files in one run",
+		Long: `Run brute forces subdomains of the target and feeds every subdomain found
+straight into port checking and file extraction while the brute force continues.
+All results are collected into one summary at the end.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if target == "" {
+				fmt.Println("\033[1;31m[!] Error: Target domain is required\033[0m")
+				cmd.Help()
+				os.Exit(1)
+			}
+
+			if format != "csv" && format != "json" {
+				fmt.Printf("\033[1;31m[!] Error: Unknown output format %q (use csv or json)\033[0m\n", format)
+				os.Exit(1)
+			}
+
+			if wordlist == "" {
+				fmt.Printf("\033[1;33m[!] Warning: No wordlist specified, using the built-in %s wordlist\033[0m\n", scanner.DefaultWordlist)
+				wordlist = scanner.DefaultWordlist
+			}
+
+			if scanThreads < 1 {
+				scanThreads = 1
+			}
+
+			logger := utils.NewLogger(verbose, nil)
+			results := scanner.NewResultManager(outputFile, flags.outputDir, logger)
+			results.SetOutputFormat(format)
+
+			options, err := flags.options(logger, results)
+			if err != nil {
+				fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
+				os.Exit(1)
+			}
+			defer options.ipDatabase.Close()
+
+			bruteForce := scanner.NewScanner(scanner.Config{
+				Target:     target,
+				Wordlist:   wordlist,
+				HitStats:   hitStats,
+				Threads:    threads,
+				Verbose:    verbose,
+				IPDatabase: options.ipDatabase,
+			})
+			if err := passive.addCandidates(bruteForce, target); err != nil {
+				fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
+				os.Exit(1)
+			}
+
+			// Stage 1: record every brute force result and pass the hits on
+			found := bruteForce.Results()
+			hosts := make(chan scanner.ScanResult, scanThreads)
+			go func() {
+				defer close(hosts)
+				for result := range found {
+					results.AddScanResult(result)
+					if result.Found {
+						hosts <- result
+					}
+				}
+			}()
+
+			// Stage 2: check the ports of each host found
+			extract := make(chan string, scanThreads)
+			var servicesWG sync.WaitGroup
+			for i := 0; i < scanThreads; i++ {
+				servicesWG.Add(1)
+				go func() {
+					defer servicesWG.Done()
+					for host := range hosts {
+						if options.skipCDN && host.Attribution.IsEdge() {
+							logger.Warning("Skipping %s: shared %s edge", host.Subdomain, host.Attribution.Provider)
+							continue
+						}
+						if options.checkPorts {
+							services := scanner.CheckCommonPorts(host.Subdomain, host.IP, options.portThreads)
+							for _, service := range services {
+								service.Network = host.Network
+								service.Attribution = host.Attribution
+								results.AddServiceResult(service.Subdomain, service.Port, service.Service, serviceSummary(service))
+							}
+						}
+						extract <- host.Subdomain
+					}
+				}()
+			}
+			go func() {
+				servicesWG.Wait()
+				close(extract)
+			}()
+
+			// Stage 3: extract files from each scanned host
+			var extractWG sync.WaitGroup
+			for i := 0; i < scanThreads; i++ {
+				extractWG.Add(1)
+				go func() {
+					defer extractWG.Done()
+					for subdomain := range extract {
+						if !options.extractFiles {
+							continue
+						}
+						if err := scanner.ExtractFiles(subdomain, options.extract); err != nil {
+							logger.Error("Failed to extract files from %s: %v", subdomain, err)
+						}
+					}
+				}()
+			}
+
+			bruteForce.Start()
+			extractWG.Wait()
+
+			if err := results.SaveAllResults(); err != nil {
+				fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
+			}
+			fmt.Print(results.GenerateSummary())
+		},
+	}
+
+	// Add flags
+	runCmd.Flags().StringVarP(&target, "target", "t", "", "Target domain to scan (required)")
+	runCmd.Flags().StringVarP(&wordlist, "wordlist", "w", "", "Wordlist file, plain or gzip compressed, or built-in list (small, medium, large)")
+	runCmd.Flags().StringVarP(&hitStats, "hit-stats", "", scanner.DefaultHitStatsPath(), "File recording which wordlist entries resolved, used by wordlist optimise (empty to disable)")
+	runCmd.Flags().IntVarP(&threads, "threads", "c", 50, "Number of concurrent brute force threads")
+	runCmd.Flags().IntVarP(&scanThreads, "scan-threads", "", 10, "Number of hosts to scan concurrently")
+	runCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output file to save found subdomains")
+	runCmd.Flags().StringVarP(&format, "format", "f", "csv", "Output file format (csv or json)")
+	runCmd.Flags().StringVarP(&flags.outputDir, "output-dir", "d", "./output", "Output directory for service and file results")
+	runCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
+	flags.register(runCmd)
+	passive.register(runCmd)
+
+	return runCmd
+}
+
+// serviceSummary condenses the HTTP probe information of a service into a
+// single line for the service results file
+func serviceSummary(service scanner.ServiceInfo) string {
+	var parts []string
+
+	if service.StatusCode > 0 {
+		parts = append(parts, fmt.Sprintf("status=%d", service.StatusCode))
+	}
+	if service.Title != "" {
+		parts = append(parts, fmt.Sprintf("title=%q", service.Title))
+	}
+	if service.Server != "" {
+		parts = append(parts, fmt.Sprintf("server=%q", service.Server))
+	}
+	if len(service.Technologies) > 0 {
+		parts = append(parts, "tech="+strings.Join(service.Technologies, "|"))
+	}
+	if service.Network != nil {
+		parts = append(parts, fmt.Sprintf("network=%q", service.Network.Owner()))
+	}
+	if service.Attribution != nil {
+		parts = append(parts, fmt.Sprintf("provider=%q", service.Attribution))
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -2,24 +2,28 @@ package cmd
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"fmt"
-	"net"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/SayerLinux/sub/pkg/scanner"
+	"github.com/SayerLinux/sub/pkg/utils"
 	"github.com/spf13/cobra"
 )
 
 // NewScanCmd creates the scan command
 func NewScanCmd() *cobra.Command {
 	var (
-		target     string
-		outputDir  string
-		checkPorts bool
-		extractFiles bool
+		target  string
+		threads int
+		flags   serviceFlags
 	)
 
 	scanCmd := &cobra.Command{
@@ -33,137 +37,420 @@ func NewScanCmd() *cobra.Command {
 				os.Exit(1)
 			}
 
-			// Create output directory if it doesn't exist
-			if outputDir == "" {
-				outputDir = "./output"
+			if threads < 1 {
+				threads = 1
 			}
 
-			err := os.MkdirAll(outputDir, 0755)
+			logger := utils.NewLogger(false, nil)
+			results := scanner.NewResultManager("", flags.outputDir, logger)
+
+			options, err := flags.options(logger, results)
 			if err != nil {
-				fmt.Printf("\033[1;31m[!] Error: Failed to create output directory: %v\033[0m\n", err)
+				fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
 				os.Exit(1)
 			}
+			defer options.ipDatabase.Close()
 
-			// Read subdomains from file if target is a file
-			var subdomains []string
-			if strings.HasSuffix(target, ".txt") {
-				file, err := os.Open(target)
-				if err != nil {
-					fmt.Printf("\033[1;31m[!] Error: Failed to open file: %v\033[0m\n", err)
-					os.Exit(1)
-				}
-				defer file.Close()
+			// Read targets from stdin, a file in any supported format or the flag itself
+			targets, err := utils.LoadTargets(target)
+			if err != nil {
+				fmt.Printf("\033[1;31m[!] Error: Failed to load targets: %v\033[0m\n", err)
+				os.Exit(1)
+			}
 
-				scanner := bufio.NewScanner(file)
-				for scanner.Scan() {
-					subdomain := strings.TrimSpace(scanner.Text())
-					if subdomain != "" && !strings.HasPrefix(subdomain, "#") {
-						subdomains = append(subdomains, subdomain)
-					}
-				}
+			subdomains, extraPorts := groupTargets(targets)
+
+			fmt.Printf("\033[1;34m[*] Scanning %d subdomains with %d threads...\033[0m\n", len(subdomains), threads)
 
-				if err := scanner.Err(); err != nil {
-					fmt.Printf("\033[1;31m[!] Error: Failed to read file: %v\033[0m\n", err)
-					os.Exit(1)
+			networks := scanHosts(os.Stdout, subdomains, threads, func(out io.Writer, subdomain string) *scanner.IPInfo {
+				return scanHost(out, subdomain, extraPorts[subdomain], options)
+			})
+
+			if options.extractFiles {
+				if err := results.SaveFileResults(); err != nil {
+					fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
+				}
+				if err := results.SaveSecretResults(); err != nil {
+					fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
 				}
-			} else {
-				// Single subdomain
-				subdomains = append(subdomains, target)
 			}
 
-			fmt.Printf("\033[1;34m[*] Scanning %d subdomains...\033[0m\n", len(subdomains))
+			fmt.Println("\n\033[1;32m[+] Scan completed!\033[0m")
+			if options.ipDatabase != nil {
+				fmt.Println("\033[1;34m[*] Hosts by network owner:\033[0m")
+				fmt.Print(scanner.FormatNetworkGroups(scanner.GroupByNetwork(networks)))
+			}
+		},
+	}
+
+	// Add flags
+	scanCmd.Flags().StringVarP(&target, "target", "t", "", "Target domain, URL or host:port, a file of targets (text, CSV or JSON) or - for stdin")
+	scanCmd.Flags().StringVarP(&flags.outputDir, "output-dir", "o", "./output", "Output directory for scan results")
+	scanCmd.Flags().IntVarP(&threads, "threads", "c", 10, "Number of hosts to scan concurrently")
+	flags.register(scanCmd)
+
+	return scanCmd
+}
+
+// scanHosts scans the hosts with a bounded pool of workers. Each host's report
+// is buffered and written to out in one piece when it is done, so reports of
+// hosts scanned at the same time never interleave. It returns the network
+// each host resolved to.
+func scanHosts(out io.Writer, subdomains []string, threads int, scan func(out io.Writer, subdomain string) *scanner.IPInfo) map[string]*scanner.IPInfo {
+	jobs := make(chan string, threads)
+	networks := make(map[string]*scanner.IPInfo)
+	var (
+		wg       sync.WaitGroup
+		outputMu sync.Mutex
+	)
 
-			// Process each subdomain
-			for _, subdomain := range subdomains {
-				fmt.Printf("\033[1;34m[*] Processing: %s\033[0m\n", subdomain)
+	for i := 0; i < threads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for subdomain := range jobs {
+				var report bytes.Buffer
+				network := scan(&report, subdomain)
 
-				// Resolve IP
-				ips, err := net.LookupIP(subdomain)
-				if err != nil {
-					fmt.Printf("\033[1;31m[!] Could not resolve %s: %v\033[0m\n", subdomain, err)
-					continue
-				}
+				outputMu.Lock()
+				out.Write(report.Bytes())
+				networks[subdomain] = network
+				outputMu.Unlock()
+			}
+		}()
+	}
+
+	for _, subdomain := range subdomains {
+		jobs <- subdomain
+	}
+	close(jobs)
+	wg.Wait()
+
+	return networks
+}
+
+// serviceFlags holds the service scan and file extraction flags shared by
+// the scan and run commands
+type serviceFlags struct {
+	outputDir      string
+	checkPorts     bool
+	extractFiles   bool
+	techSignatures string
+	pathsFile      string
+	dumpGit        bool
+	gitMaxObjects  int
+	gitMaxSize     int64
+	scanSecrets    bool
+	revealSecrets  bool
+	portThreads    int
+	pathThreads    int
+	ipDatabases    []string
+	providers      string
+	skipCDN        bool
+	scope          string
+	proxies        []string
+	resolvers      []string
+	http           httpFlags
+}
+
+// register adds the service scan and file extraction flags to a command
+func (f *serviceFlags) register(cmd *cobra.Command) {
+	cmd.Flags().IntVarP(&f.portThreads, "port-threads", "", 8, "Number of concurrent port checks per host")
+	cmd.Flags().IntVarP(&f.pathThreads, "path-threads", "", 5, "Number of concurrent path checks per host")
+	cmd.Flags().BoolVarP(&f.checkPorts, "check-ports", "p", true, "Check for open ports and services")
+	cmd.Flags().BoolVarP(&f.extractFiles, "extract-files", "e", true, "Attempt to extract sensitive files")
+	cmd.Flags().StringVarP(&f.pathsFile, "paths", "", "", "Wordlist of paths to check during file extraction")
+	cmd.Flags().BoolVarP(&f.dumpGit, "dump-git", "", true, "Reconstruct exposed .git repositories found during file extraction")
+	cmd.Flags().IntVarP(&f.gitMaxObjects, "git-max-objects", "", scanner.DefaultGitMaxObjects, "Maximum number of git objects to download per repository")
+	cmd.Flags().Int64VarP(&f.gitMaxSize, "git-max-size", "", scanner.DefaultGitMaxSize>>20, "Maximum size in MB to download per git repository")
+	cmd.Flags().BoolVarP(&f.scanSecrets, "scan-secrets", "", true, "Scan extracted files for keys, passwords and tokens")
+	cmd.Flags().BoolVarP(&f.revealSecrets, "reveal-secrets", "", false, "Report secret values unmasked")
+	cmd.Flags().StringVarP(&f.techSignatures, "tech-signatures", "", "", "JSON file with technology signatures to use instead of the built-in ones")
+	cmd.Flags().StringVarP(&f.providers, "providers", "", "", "JSON file with cloud, CDN and WAF signatures to use instead of the built-in ones")
+	cmd.Flags().BoolVarP(&f.skipCDN, "skip-cdn", "", false, "Skip port checks and file extraction on shared CDN and WAF edge IPs")
+	cmd.Flags().StringVarP(&f.scope, "scope", "", "", "File of domain patterns, IPs and CIDRs that may be contacted, with ! exclusions")
+	f.http.register(cmd)
+	cmd.Flags().StringSliceVarP(&f.resolvers, "resolvers", "", nil, "DNS resolver IPs (optionally ip:port) or a file of them, used in turn instead of the system resolver")
+	cmd.Flags().StringSliceVarP(&f.proxies, "proxy", "", nil, "HTTP or SOCKS5 proxy URL (e.g. http://127.0.0.1:8080 for Burp) or file of proxies, rotated per connection (repeatable)")
+	cmd.Flags().StringSliceVarP(&f.ipDatabases, "ip-db", "", nil, "iptoasn TSV or MMDB file used to add ASN, organisation and country to IPs (repeatable)")
+}
+
+// options loads the files referenced by the flags, creates the output
+// directory and returns the per-host scan options. The caller closes the IP
+// database of the options.
+func (f *serviceFlags) options(logger *utils.Logger, results *scanner.ResultManager) (scanOptions, error) {
+	if f.techSignatures != "" {
+		if err := scanner.LoadTechSignatures(f.techSignatures); err != nil {
+			return scanOptions{}, err
+		}
+	}
+
+	if f.providers != "" {
+		if err := scanner.LoadProviderSignatures(f.providers); err != nil {
+			return scanOptions{}, err
+		}
+	}
+
+	if f.scope != "" {
+		if err := scanner.LoadScope(f.scope); err != nil {
+			return scanOptions{}, err
+		}
+	}
+
+	if err := loadProxies(f.proxies); err != nil {
+		return scanOptions{}, err
+	}
+	if err := utils.LoadResolvers(f.resolvers...); err != nil {
+		return scanOptions{}, err
+	}
+	if err := f.http.configure(); err != nil {
+		return scanOptions{}, err
+	}
+
+	// Create output directory if it doesn't exist
+	if f.outputDir == "" {
+		f.outputDir = "./output"
+	}
+
+	err := os.MkdirAll(f.outputDir, 0755)
+	if err != nil {
+		return scanOptions{}, fmt.Errorf("failed to create output directory: %v", err)
+	}
+
+	// Load the path wordlist used for file extraction
+	var paths []string
+	if f.pathsFile != "" {
+		paths, err = utils.LoadWordlist(f.pathsFile)
+		if err != nil {
+			return scanOptions{}, err
+		}
+	}
+
+	// Load the databases used to enrich resolved IPs
+	var ipDatabase *scanner.IPDatabase
+	if len(f.ipDatabases) > 0 {
+		ipDatabase, err = scanner.LoadIPDatabase(f.ipDatabases...)
+		if err != nil {
+			return scanOptions{}, err
+		}
+	}
 
-				ip := ips[0].String()
-				fmt.Printf("\033[1;32m[+] Resolved %s to %s\033[0m\n", subdomain, ip)
-
-				// Check ports if enabled
-				if checkPorts {
-					fmt.Printf("\033[1;34m[*] Checking common ports on %s...\033[0m\n", subdomain)
-					services := scanner.CheckCommonPorts(subdomain, ip)
-
-					if len(services) > 0 {
-						fmt.Printf("\033[1;32m[+] Found %d open ports on %s\033[0m\n", len(services), subdomain)
-
-						// Save service information
-						serviceFile := filepath.Join(outputDir, fmt.Sprintf("%s_services.txt", subdomain))
-						file, err := os.Create(serviceFile)
-						if err == nil {
-							defer file.Close()
-							writer := bufio.NewWriter(file)
-
-							writer.WriteString(fmt.Sprintf("# Service Scan Results for %s\n", subdomain))
-							writer.WriteString(fmt.Sprintf("# Generated by Sub Tool - By SayerLinux (SaudiSayer@gmail.com)\n"))
-							writer.WriteString(fmt.Sprintf("# Date: %s\n\n", time.Now().Format(time.RFC1123)))
-
-							for _, service := range services {
-								writer.WriteString(fmt.Sprintf("Port: %d\nService: %s\n", service.Port, service.Service))
-								if service.StatusCode > 0 {
-									writer.WriteString(fmt.Sprintf("Status Code: %d\n", service.StatusCode))
-								}
-								if service.Server != "" {
-									writer.WriteString(fmt.Sprintf("Server: %s\n", service.Server))
-								}
-								if service.Title != "" {
-									writer.WriteString(fmt.Sprintf("Title: %s\n", service.Title))
-								}
-								writer.WriteString("\n")
-							}
-
-							writer.Flush()
-							fmt.Printf("\033[1;32m[+] Service information saved to %s\033[0m\n", serviceFile)
-						}
-
-						// Display service information
-						for _, service := range services {
-							fmt.Printf("\033[1;32m[+] %s:%d - %s\033[0m\n", subdomain, service.Port, service.Service)
-							if service.StatusCode > 0 {
-								fmt.Printf("    Status Code: %d\n", service.StatusCode)
-							}
-							if service.Server != "" {
-								fmt.Printf("    Server: %s\n", service.Server)
-							}
-							if service.Title != "" {
-								fmt.Printf("    Title: %s\n", service.Title)
-							}
-						}
-					} else {
-						fmt.Printf("\033[1;33m[!] No open ports found on %s\033[0m\n", subdomain)
+	return scanOptions{
+		outputDir:    f.outputDir,
+		ipDatabase:   ipDatabase,
+		skipCDN:      f.skipCDN,
+		checkPorts:   f.checkPorts,
+		portThreads:  f.portThreads,
+		extractFiles: f.extractFiles,
+		extract: scanner.ExtractConfig{
+			OutputDir: f.outputDir,
+			Paths:     paths,
+			Threads:   f.pathThreads,
+			Results:   results,
+			DumpGit:   f.dumpGit,
+			GitDump: scanner.GitDumpConfig{
+				MaxObjects: f.gitMaxObjects,
+				MaxSize:    f.gitMaxSize << 20,
+				Logger:     logger,
+			},
+			ScanSecrets:   f.scanSecrets,
+			RevealSecrets: f.revealSecrets,
+		},
+	}, nil
+}
+
+// scanOptions holds the settings shared by every host of a scan
+type scanOptions struct {
+	outputDir    string
+	checkPorts   bool
+	portThreads  int
+	extractFiles bool
+	extract      scanner.ExtractConfig
+	ipDatabase   *scanner.IPDatabase
+	skipCDN      bool
+}
+
+// scanHost resolves a subdomain, checks its ports and extracts its files,
+// writing the report for the host to out. It returns the network the host
+// resolved to, if known.
+func scanHost(out io.Writer, subdomain string, ports []scanner.Port, options scanOptions) *scanner.IPInfo {
+	fmt.Fprintf(out, "\033[1;34m[*] Processing: %s\033[0m\n", subdomain)
+
+	if !scanner.InScope(subdomain) {
+		fmt.Fprintf(out, "\033[1;33m[!] Skipping out of scope: %s\033[0m\n", subdomain)
+		return nil
+	}
+
+	// Resolve IP
+	ips, err := utils.Resolver().LookupIP(context.Background(), "ip", subdomain)
+	if err != nil {
+		fmt.Fprintf(out, "\033[1;31m[!] Could not resolve %s: %v\033[0m\n", subdomain, err)
+		return nil
+	}
+
+	ip := ips[0].String()
+	if !scanner.InScope(subdomain, ip) {
+		fmt.Fprintf(out, "\033[1;33m[!] Skipping out of scope: %s -> %s\033[0m\n", subdomain, ip)
+		return nil
+	}
+
+	network := options.ipDatabase.Lookup(ip)
+	if network != nil {
+		fmt.Fprintf(out, "\033[1;32m[+] Resolved %s to %s (%s)\033[0m\n", subdomain, ip, network.Owner())
+	} else {
+		fmt.Fprintf(out, "\033[1;32m[+] Resolved %s to %s\033[0m\n", subdomain, ip)
+	}
+
+	attribution := scanner.AttributeHost(subdomain, ip)
+	if attribution != nil {
+		fmt.Fprintf(out, "\033[1;34m[*] %s is hosted by %s\033[0m\n", subdomain, attribution)
+	}
+	if options.skipCDN && attribution.IsEdge() {
+		fmt.Fprintf(out, "\033[1;33m[!] Skipping %s: shared %s edge\033[0m\n", subdomain, attribution.Provider)
+		return network
+	}
+
+	// Check ports if enabled
+	if options.checkPorts {
+		fmt.Fprintf(out, "\033[1;34m[*] Checking common ports on %s...\033[0m\n", subdomain)
+		services := scanner.CheckCommonPorts(subdomain, ip, options.portThreads, ports...)
+		for i := range services {
+			services[i].Network = network
+			services[i].Attribution = attribution
+		}
+
+		if len(services) > 0 {
+			fmt.Fprintf(out, "\033[1;32m[+] Found %d open ports on %s\033[0m\n", len(services), subdomain)
+
+			// Save service information
+			serviceFile := filepath.Join(options.outputDir, fmt.Sprintf("%s_services.txt", subdomain))
+			file, err := os.Create(serviceFile)
+			if err == nil {
+				defer file.Close()
+				writer := bufio.NewWriter(file)
+
+				writer.WriteString(fmt.Sprintf("# Service Scan Results for %s\n", subdomain))
+				writer.WriteString(fmt.Sprintf("# Generated by Sub Tool - By SayerLinux (SaudiSayer@gmail.com)\n"))
+				writer.WriteString(fmt.Sprintf("# Date: %s\n\n", time.Now().Format(time.RFC1123)))
+
+				for _, service := range services {
+					writer.WriteString(fmt.Sprintf("Port: %d\nService: %s\n", service.Port, service.Service))
+					for _, line := range serviceDetails(service) {
+						writer.WriteString(line + "\n")
 					}
+					writer.WriteString("\n")
 				}
 
-				// Extract files if enabled
-				if extractFiles {
-					fmt.Printf("\033[1;34m[*] Attempting to extract files from %s...\033[0m\n", subdomain)
-					subdomainDir := filepath.Join(outputDir, subdomain)
-					err := scanner.ExtractFiles(subdomain, outputDir)
-					if err != nil {
-						fmt.Printf("\033[1;31m[!] Error extracting files: %v\033[0m\n", err)
-					} else {
-						fmt.Printf("\033[1;32m[+] Files extracted to %s\033[0m\n", subdomainDir)
-					}
+				writer.Flush()
+				fmt.Fprintf(out, "\033[1;32m[+] Service information saved to %s\033[0m\n", serviceFile)
+			}
+
+			// Display service information
+			for _, service := range services {
+				fmt.Fprintf(out, "\033[1;32m[+] %s:%d - %s\033[0m\n", subdomain, service.Port, service.Service)
+				for _, line := range serviceDetails(service) {
+					fmt.Fprintf(out, "    %s\n", line)
 				}
 			}
+		} else {
+			fmt.Fprintf(out, "\033[1;33m[!] No open ports found on %s\033[0m\n", subdomain)
+		}
+	}
 
-			fmt.Println("\n\033[1;32m[+] Scan completed!\033[0m")
-		},
+	// Extract files if enabled
+	if options.extractFiles {
+		fmt.Fprintf(out, "\033[1;34m[*] Attempting to extract files from %s...\033[0m\n", subdomain)
+		subdomainDir := filepath.Join(options.outputDir, subdomain)
+		err := scanner.ExtractFiles(subdomain, options.extract)
+		if err != nil {
+			fmt.Fprintf(out, "\033[1;31m[!] Error extracting files: %v\033[0m\n", err)
+		} else {
+			fmt.Fprintf(out, "\033[1;32m[+] Files extracted to %s\033[0m\n", subdomainDir)
+		}
 	}
 
-	// Add flags
-	scanCmd.Flags().StringVarP(&target, "target", "t", "", "Target domain or file containing list of domains")
-	scanCmd.Flags().StringVarP(&outputDir, "output-dir", "o", "", "Output directory for scan results")
-	scanCmd.Flags().BoolVarP(&checkPorts, "check-ports", "p", true, "Check for open ports and services")
-	scanCmd.Flags().BoolVarP(&extractFiles, "extract-files", "e", true, "Attempt to extract sensitive files")
+	return network
+}
 
-	return scanCmd
-}
\ No newline at end of file
+// groupTargets groups the explicit ports of the targets by host, so each host
+// is scanned once. Hosts keep the order they first appear in.
+func groupTargets(targets []utils.Target) ([]string, map[string][]scanner.Port) {
+	var subdomains []string
+	extraPorts := make(map[string][]scanner.Port)
+	for _, t := range targets {
+		if _, ok := extraPorts[t.Host]; !ok {
+			subdomains = append(subdomains, t.Host)
+			extraPorts[t.Host] = nil
+		}
+		if t.Port != 0 {
+			extraPorts[t.Host] = append(extraPorts[t.Host], targetPort(t))
+		}
+	}
+	return subdomains, extraPorts
+}
+
+// targetPort describes the explicit port of a target, using its URL scheme
+// to decide whether the port is probed over HTTP or HTTPS
+func targetPort(target utils.Target) scanner.Port {
+	switch target.Scheme {
+	case "http":
+		return scanner.Port{Port: target.Port, Service: "HTTP"}
+	case "https":
+		return scanner.Port{Port: target.Port, Service: "HTTPS"}
+	default:
+		return scanner.Port{Port: target.Port, Service: "TCP"}
+	}
+}
+
+// serviceDetails formats the HTTP probe information of a service as "Key: value" lines
+func serviceDetails(service scanner.ServiceInfo) []string {
+	var lines []string
+
+	if service.StatusCode > 0 {
+		lines = append(lines, fmt.Sprintf("Status Code: %d", service.StatusCode))
+	}
+	for _, redirect := range service.Redirects {
+		lines = append(lines, fmt.Sprintf("Redirect: %s -> %s (%d)", redirect.URL, redirect.Location, redirect.StatusCode))
+	}
+	if service.FinalURL != "" && service.FinalURL != service.URL {
+		lines = append(lines, fmt.Sprintf("Final URL: %s", service.FinalURL))
+	}
+	if service.Server != "" {
+		lines = append(lines, fmt.Sprintf("Server: %s", service.Server))
+	}
+	if service.Title != "" {
+		lines = append(lines, fmt.Sprintf("Title: %s", service.Title))
+	}
+	if service.ContentType != "" {
+		lines = append(lines, fmt.Sprintf("Content Type: %s", service.ContentType))
+	}
+	if service.BodyHash != "" {
+		lines = append(lines, fmt.Sprintf("Content Length: %d", service.ContentLength))
+		lines = append(lines, fmt.Sprintf("Body Hash: %s", service.BodyHash))
+	}
+	if service.FaviconHash != 0 {
+		lines = append(lines, fmt.Sprintf("Favicon Hash: %d", service.FaviconHash))
+	}
+	if len(service.Technologies) > 0 {
+		lines = append(lines, fmt.Sprintf("Technologies: %s", strings.Join(service.Technologies, ", ")))
+	}
+	if service.Network != nil {
+		lines = append(lines, fmt.Sprintf("Network: %s", service.Network.Owner()))
+	}
+	if service.Attribution != nil {
+		lines = append(lines, fmt.Sprintf("Provider: %s", service.Attribution))
+	}
+
+	names := make([]string, 0, len(service.Headers))
+	for name := range service.Headers {
+		if name != "Server" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("Header %s: %s", name, service.Headers[name]))
+	}
+
+	return lines
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SayerLinux/sub/pkg/scanner"
+	"github.com/SayerLinux/sub/pkg/utils"
+)
+
+func TestScanHostsReportsPerHost(t *testing.T) {
+	var subdomains []string
+	for i := 0; i < 20; i++ {
+		subdomains = append(subdomains, fmt.Sprintf("host%02d.example.com", i))
+	}
+
+	var (
+		mutex          sync.Mutex
+		active, most   int
+		out            bytes.Buffer
+		linesPerReport = 5
+	)
+	networks := scanHosts(&out, subdomains, 4, func(w io.Writer, subdomain string) *scanner.IPInfo {
+		mutex.Lock()
+		active++
+		most = max(most, active)
+		mutex.Unlock()
+
+		for i := 0; i < linesPerReport; i++ {
+			fmt.Fprintf(w, "%s line %d\n", subdomain, i)
+			time.Sleep(time.Millisecond)
+		}
+
+		mutex.Lock()
+		active--
+		mutex.Unlock()
+		return &scanner.IPInfo{Organisation: subdomain}
+	})
+
+	if most > 4 {
+		t.Errorf("%d hosts scanned at once, want at most 4", most)
+	}
+
+	// Every report is written in one piece
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != len(subdomains)*linesPerReport {
+		t.Fatalf("got %d lines, want %d", len(lines), len(subdomains)*linesPerReport)
+	}
+	seen := make(map[string]bool)
+	for start := 0; start < len(lines); start += linesPerReport {
+		host := strings.Fields(lines[start])[0]
+		if seen[host] {
+			t.Fatalf("report of %s written in several pieces", host)
+		}
+		seen[host] = true
+		for i := 0; i < linesPerReport; i++ {
+			if want := fmt.Sprintf("%s line %d", host, i); lines[start+i] != want {
+				t.Fatalf("line %d = %q, want %q", start+i, lines[start+i], want)
+			}
+		}
+	}
+
+	if len(networks) != len(subdomains) {
+		t.Fatalf("got %d networks, want %d", len(networks), len(subdomains))
+	}
+	for _, subdomain := range subdomains {
+		if networks[subdomain] == nil || networks[subdomain].Organisation != subdomain {
+			t.Errorf("network of %s = %+v", subdomain, networks[subdomain])
+		}
+	}
+}
+
+func TestGroupTargets(t *testing.T) {
+	targets, err := utils.ReadTargets(strings.NewReader(`# hosts from the last run
+www.example.com
+api.example.com:8443
+https://www.example.com:9443/login
+http://api.example.com:8080
+www.example.com
+api.example.com:8443
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subdomains, ports := groupTargets(targets)
+	if !reflect.DeepEqual(subdomains, []string{"www.example.com", "api.example.com"}) {
+		t.Errorf("subdomains = %v", subdomains)
+	}
+
+	want := map[string][]scanner.Port{
+		"www.example.com": {{Port: 9443, Service: "HTTPS"}},
+		"api.example.com": {{Port: 8443, Service: "TCP"}, {Port: 8080, Service: "HTTP"}},
+	}
+	if !reflect.DeepEqual(ports, want) {
+		t.Errorf("ports = %v, want %v", ports, want)
+	}
+}
@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+
+	"github.com/SayerLinux/sub/pkg/scanner"
+	"github.com/SayerLinux/sub/pkg/utils"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// NewVhostCmd creates the vhost command, which finds virtual hosts served
+// from the addresses of a target that DNS does not reveal
+func NewVhostCmd() *cobra.Command {
+	var (
+		target     string
+		wordlist   string
+		ips        []string
+		ports      []int
+		threads    int
+		outputFile string
+		format     string
+		verbose    bool
+		scope      string
+		proxies    []string
+		resolvers  []string
+		http       httpFlags
+	)
+
+	vhostCmd := &cobra.Command{
+		Use:   "vhost",
+		Short: "Discover virtual hosts by Host header and SNI fuzzing",
+		Long: `Vhost sends HTTP and HTTPS requests to the addresses of a target with Host
+and SNI values built from the wordlist, and reports the names whose response
+differs from the one given for a random host. The addresses are taken from
+--ips, or found by brute forcing the target first.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if target == "" {
+				fmt.Println("\033[1;31m[!] Error: Target domain is required\033[0m")
+				cmd.Help()
+				os.Exit(1)
+			}
+
+			if format != "csv" && format != "json" {
+				fmt.Printf("\033[1;31m[!] Error: Unknown output format %q (use csv or json)\033[0m\n", format)
+				os.Exit(1)
+			}
+
+			if wordlist == "" {
+				fmt.Printf("\033[1;33m[!] Warning: No wordlist specified, using the built-in %s wordlist\033[0m\n", scanner.DefaultWordlist)
+				wordlist = scanner.DefaultWordlist
+			}
+
+			if scope != "" {
+				if err := scanner.LoadScope(scope); err != nil {
+					fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
+					os.Exit(1)
+				}
+			}
+
+			if err := loadProxies(proxies); err != nil {
+				fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
+				os.Exit(1)
+			}
+
+			if err := utils.LoadResolvers(resolvers...); err != nil {
+				fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
+				os.Exit(1)
+			}
+			if err := http.configure(); err != nil {
+				fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
+				os.Exit(1)
+			}
+
+			words, err := scanner.LoadWordlist(wordlist)
+			if err != nil {
+				fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
+				os.Exit(1)
+			}
+
+			var addresses []string
+			if len(ips) > 0 {
+				addresses, err = loadAddresses(ips)
+			} else {
+				addresses = bruteForceAddresses(target, wordlist, threads, verbose)
+			}
+			if err != nil {
+				fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
+				os.Exit(1)
+			}
+			if len(addresses) == 0 {
+				fmt.Println("\033[1;33m[!] Warning: No addresses to probe\033[0m")
+				return
+			}
+
+			fmt.Printf("\033[1;34m[*] Probing %d addresses with %d names\033[0m\n", len(addresses), len(words)+1)
+
+			var results []scanner.ScanResult
+			green := color.New(color.FgGreen).SprintFunc()
+			found := make(chan scanner.VhostResult)
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for vhost := range found {
+					fmt.Printf("%s %s @ %s (%s:%d) status=%d length=%d", green("[+]"), vhost.Host, vhost.IP, vhost.Scheme, vhost.Port, vhost.StatusCode, vhost.ContentLength)
+					if vhost.Title != "" {
+						fmt.Printf(" title=%q", vhost.Title)
+					}
+					fmt.Println()
+
+					results = append(results, scanner.ScanResult{
+						Subdomain: vhost.Host,
+						IP:        vhost.IP,
+						Found:     true,
+						Sources:   []string{scanner.SourceVhost},
+					})
+				}
+			}()
+
+			scanner.DiscoverVhosts(addresses, scanner.VhostConfig{
+				Domain:  target,
+				Words:   words,
+				Ports:   ports,
+				Threads: threads,
+			}, func(vhost scanner.VhostResult) {
+				found <- vhost
+			})
+			close(found)
+			<-done
+
+			fmt.Printf("\033[1;34m[*] Found %d virtual hosts\033[0m\n", len(results))
+
+			if outputFile != "" {
+				file, err := os.Create(outputFile)
+				if err != nil {
+					fmt.Printf("\033[1;31m[!] Error: Failed to create output file: %v\033[0m\n", err)
+					os.Exit(1)
+				}
+				defer file.Close()
+
+				if err := scanner.WriteResults(file, target, format, results); err != nil {
+					fmt.Printf("\033[1;31m[!] Error: Failed to write output file: %v\033[0m\n", err)
+					os.Exit(1)
+				}
+				fmt.Printf("\033[1;32m[+] Results saved to %s\033[0m\n", outputFile)
+			}
+		},
+	}
+
+	// Add flags
+	vhostCmd.Flags().StringVarP(&target, "target", "t", "", "Target domain the virtual host names are built from (required)")
+	vhostCmd.Flags().StringVarP(&wordlist, "wordlist", "w", "", "Wordlist file, plain or gzip compressed, or built-in list (small, medium, large)")
+	vhostCmd.Flags().StringSliceVarP(&ips, "ips", "", nil, "Addresses to probe, or files of addresses and hosts such as a previous scan output (default: brute force the target)")
+	vhostCmd.Flags().IntSliceVarP(&ports, "ports", "p", scanner.DefaultVhostPorts, "Ports to probe on each address")
+	vhostCmd.Flags().StringVarP(&scope, "scope", "", "", "File of domain patterns, IPs and CIDRs that may be contacted, with ! exclusions")
+	vhostCmd.Flags().StringSliceVarP(&resolvers, "resolvers", "", nil, "DNS resolver IPs (optionally ip:port) or a file of them, used in turn instead of the system resolver")
+	vhostCmd.Flags().StringSliceVarP(&proxies, "proxy", "", nil, "SOCKS5 proxy URL or file of proxies, rotated per connection (repeatable)")
+	http.register(vhostCmd)
+	vhostCmd.Flags().IntVarP(&threads, "threads", "c", 50, "Number of concurrent requests")
+	vhostCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output file to save results")
+	vhostCmd.Flags().StringVarP(&format, "format", "f", "csv", "Output file format (csv or json)")
+	vhostCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
+
+	return vhostCmd
+}
+
+// loadAddresses reads the addresses to probe from the --ips values. Each
+// value is an address, a host or a file of them; hosts are resolved.
+func loadAddresses(values []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var addresses []string
+	add := func(address string) {
+		if !seen[address] {
+			seen[address] = true
+			addresses = append(addresses, address)
+		}
+	}
+
+	for _, value := range values {
+		targets, err := utils.LoadTargets(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load addresses: %v", err)
+		}
+
+		for _, target := range targets {
+			if net.ParseIP(target.Host) != nil {
+				add(target.Host)
+				continue
+			}
+			resolved, err := utils.Resolver().LookupHost(context.Background(), target.Host)
+			if err != nil {
+				continue
+			}
+			for _, address := range resolved {
+				add(address)
+			}
+		}
+	}
+
+	return addresses, nil
+}
+
+// bruteForceAddresses brute forces the target and returns the unique
+// addresses of the subdomains found
+func bruteForceAddresses(target string, wordlist string, threads int, verbose bool) []string {
+	bruteForce := scanner.NewScanner(scanner.Config{
+		Target:   target,
+		Wordlist: wordlist,
+		Threads:  threads,
+		Verbose:  verbose,
+	})
+
+	seen := make(map[string]bool)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for result := range bruteForce.Results() {
+			if result.Found && !seen[result.IP] {
+				seen[result.IP] = true
+				fmt.Printf("\033[1;34m[*] Found %s -> %s\033[0m\n", result.Subdomain, result.IP)
+			}
+		}
+	}()
+	bruteForce.Start()
+	<-done
+
+	addresses := make([]string, 0, len(seen))
+	for address := range seen {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	return addresses
+}
@@ -0,0 +1,300 @@
+package cmd
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/SayerLinux/sub/pkg/scanner"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// NewWordlistCmd creates the wordlist command, which manages wordlist files
+// and the wordlists built into the binary
+func NewWordlistCmd() *cobra.Command {
+	wordlistCmd := &cobra.Command{
+		Use:   "wordlist",
+		Short: "List, merge, validate, summarise and optimise wordlists",
+		Long: `Wordlist works on wordlist files, plain or gzip compressed, and on the
+built-in lists (small, medium and large), which can be given by name
+wherever a wordlist is expected.`,
+	}
+
+	wordlistCmd.AddCommand(newWordlistListCmd())
+	wordlistCmd.AddCommand(newWordlistMergeCmd())
+	wordlistCmd.AddCommand(newWordlistValidateCmd())
+	wordlistCmd.AddCommand(newWordlistStatsCmd())
+	wordlistCmd.AddCommand(newWordlistOptimiseCmd())
+	return wordlistCmd
+}
+
+// newWordlistListCmd creates the command showing the built-in wordlists
+func newWordlistListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "Show the built-in wordlists",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			for _, name := range scanner.BuiltinWordlistNames {
+				words, err := scanner.LoadWordlist(name)
+				if err != nil {
+					fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
+					os.Exit(1)
+				}
+
+				line := fmt.Sprintf("%-8s %6d entries", name, len(words))
+				if name == scanner.DefaultWordlist {
+					line += " (default)"
+				}
+				fmt.Printf("\033[1;34m[*] %s\033[0m\n", line)
+			}
+		},
+	}
+}
+
+// newWordlistMergeCmd creates the command combining wordlists into one
+func newWordlistMergeCmd() *cobra.Command {
+	var (
+		outputFile  string
+		keepCase    bool
+		keepInvalid bool
+	)
+
+	mergeCmd := &cobra.Command{
+		Use:   "merge [flags] LIST...",
+		Short: "Merge wordlists into one without duplicates",
+		Long: `Merge writes the entries of the given wordlists to one file in order, without
+duplicates. Entries are lowercased and those that are not valid DNS labels
+are dropped unless told otherwise. The output is gzip compressed when its
+name ends in .gz.`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if outputFile == "" {
+				fmt.Println("\033[1;31m[!] Error: Output file is required\033[0m")
+				cmd.Help()
+				os.Exit(1)
+			}
+
+			var stats scanner.MergeStats
+			err := writeWordlistFile(outputFile, func(writer io.Writer) error {
+				var err error
+				stats, err = scanner.MergeWordlists(writer, args, scanner.MergeOptions{
+					Lowercase:   !keepCase,
+					DropInvalid: !keepInvalid,
+				})
+				return err
+			})
+			if err != nil {
+				fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
+				os.Exit(1)
+			}
+
+			fmt.Printf("\033[1;34m[*] Read %d entries from %d wordlists\033[0m\n", stats.Read, len(args))
+			fmt.Printf("\033[1;34m[*] Dropped %d duplicates and %d invalid entries\033[0m\n", stats.Duplicates, stats.Invalid)
+			fmt.Printf("\033[1;32m[+] Wrote %d entries to %s\033[0m\n", stats.Written, outputFile)
+		},
+	}
+
+	mergeCmd.Flags().StringVarP(&outputFile, "output", "o", "", "File to write the merged wordlist to (required)")
+	mergeCmd.Flags().BoolVarP(&keepCase, "keep-case", "", false, "Keep the case of entries instead of lowercasing them")
+	mergeCmd.Flags().BoolVarP(&keepInvalid, "keep-invalid", "", false, "Keep entries that are not valid DNS labels")
+
+	return mergeCmd
+}
+
+// newWordlistValidateCmd creates the command reporting invalid entries
+func newWordlistValidateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate LIST...",
+		Short: "Report entries that are not valid DNS labels",
+		Long: `Validate checks every entry of the given wordlists against the DNS label
+rules: 1 to 63 letters, digits, hyphens or underscores per label, no hyphen
+at either end of a label and 253 characters in all. It exits with an error
+status if any entry is invalid.`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			red := color.New(color.FgRed).SprintFunc()
+			invalid := 0
+			for _, name := range args {
+				reader, err := scanner.OpenWordlist(name)
+				if err != nil {
+					fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
+					os.Exit(1)
+				}
+
+				for reader.Scan() {
+					if err := scanner.ValidateWord(reader.Word()); err != nil {
+						invalid++
+						fmt.Printf("%s %s: %q: %v\n", red("[-]"), name, reader.Word(), err)
+					}
+				}
+				err = reader.Err()
+				reader.Close()
+				if err != nil {
+					fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
+					os.Exit(1)
+				}
+			}
+
+			if invalid > 0 {
+				fmt.Printf("\033[1;31m[!] Found %d invalid entries\033[0m\n", invalid)
+				os.Exit(1)
+			}
+			fmt.Println("\033[1;32m[+] All entries are valid\033[0m")
+		},
+	}
+}
+
+// newWordlistStatsCmd creates the command summarising wordlists
+func newWordlistStatsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "stats LIST...",
+		Short: "Show statistics about wordlists",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			for _, name := range args {
+				reader, err := scanner.OpenWordlist(name)
+				if err != nil {
+					fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
+					os.Exit(1)
+				}
+				stats, err := scanner.ComputeWordlistStats(reader)
+				reader.Close()
+				if err != nil {
+					fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
+					os.Exit(1)
+				}
+
+				fmt.Printf("\033[1;34m[*] %s\033[0m\n", name)
+				fmt.Printf("    Entries:        %d\n", stats.Entries)
+				fmt.Printf("    Unique:         %d\n", stats.Unique)
+				fmt.Printf("    Duplicates:     %d\n", stats.Duplicates)
+				fmt.Printf("    Uppercase:      %d\n", stats.Uppercase)
+				fmt.Printf("    Invalid:        %d\n", stats.Invalid)
+				fmt.Printf("    Several labels: %d\n", stats.MultiLabel)
+				fmt.Printf("    With digits:    %d\n", stats.WithDigits)
+				fmt.Printf("    With hyphens:   %d\n", stats.WithHyphens)
+				fmt.Printf("    Length:         %d to %d, %.1f on average\n", stats.MinLength, stats.MaxLength, stats.AverageLength)
+			}
+		},
+	}
+}
+
+// newWordlistOptimiseCmd creates the command reordering a wordlist by the
+// hit statistics recorded by earlier scans
+func newWordlistOptimiseCmd() *cobra.Command {
+	var (
+		outputFile string
+		statsFile  string
+		top        int
+	)
+
+	optimiseCmd := &cobra.Command{
+		Use:     "optimise [flags] LIST",
+		Aliases: []string{"optimize"},
+		Short:   "Reorder a wordlist by how often its entries resolved",
+		Long: `Optimise reorders a wordlist so the entries that resolved most often in
+earlier scans come first, using the hit statistics every scan records. Entries
+with the same number of hits keep their order. With --top only the first N
+entries are written, for quick scans that find most names in a fraction of
+the lookups. The output is gzip compressed when its name ends in .gz.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if outputFile == "" {
+				fmt.Println("\033[1;31m[!] Error: Output file is required\033[0m")
+				cmd.Help()
+				os.Exit(1)
+			}
+
+			stats, err := scanner.LoadHitStats(statsFile)
+			if err != nil {
+				fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
+				os.Exit(1)
+			}
+			hits := stats.Lookup(args[0])
+			if hits == nil || hits.Scans == 0 {
+				fmt.Printf("\033[1;31m[!] Error: No hit statistics recorded for %s in %s\033[0m\n", args[0], statsFile)
+				os.Exit(1)
+			}
+
+			words, err := scanner.LoadWordlist(args[0])
+			if err != nil {
+				fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
+				os.Exit(1)
+			}
+
+			ranked := hits.Rank(words)
+			total, withHits := 0, 0
+			for _, word := range ranked {
+				if count := hits.Count(word); count > 0 {
+					total += count
+					withHits++
+				}
+			}
+			if top > 0 && top < len(ranked) {
+				ranked = ranked[:top]
+			}
+			covered := 0
+			for _, word := range ranked {
+				covered += hits.Count(word)
+			}
+
+			err = writeWordlistFile(outputFile, func(writer io.Writer) error {
+				for _, word := range ranked {
+					if _, err := fmt.Fprintln(writer, word); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
+				os.Exit(1)
+			}
+
+			fmt.Printf("\033[1;34m[*] %d of %d entries resolved over %d scanned domains\033[0m\n", withHits, len(words), hits.Scans)
+			if len(ranked) > 0 {
+				fmt.Printf("\033[1;34m[*] Top entry: %s (%.1f%% of scans)\033[0m\n", ranked[0], hits.Rate(ranked[0])*100)
+			}
+			if total > 0 && len(ranked) < len(words) {
+				fmt.Printf("\033[1;34m[*] The first %d entries (%.1f%% of the list) account for %.1f%% of the hits\033[0m\n",
+					len(ranked), float64(len(ranked))*100/float64(len(words)), float64(covered)*100/float64(total))
+			}
+			fmt.Printf("\033[1;32m[+] Wrote %d entries to %s\033[0m\n", len(ranked), outputFile)
+		},
+	}
+
+	optimiseCmd.Flags().StringVarP(&outputFile, "output", "o", "", "File to write the reordered wordlist to (required)")
+	optimiseCmd.Flags().StringVarP(&statsFile, "hit-stats", "", scanner.DefaultHitStatsPath(), "Hit statistics file recorded by earlier scans")
+	optimiseCmd.Flags().IntVarP(&top, "top", "n", 0, "Write only the N entries with the most hits")
+
+	return optimiseCmd
+}
+
+// writeWordlistFile creates a wordlist file and fills it through write,
+// gzip compressing it when its name ends in .gz
+func writeWordlistFile(path string, write func(io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %v", err)
+	}
+
+	var writer io.Writer = file
+	var compressed *gzip.Writer
+	if strings.HasSuffix(path, ".gz") {
+		compressed = gzip.NewWriter(file)
+		writer = compressed
+	}
+
+	err = write(writer)
+	if err == nil && compressed != nil {
+		err = compressed.Close()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -9,7 +9,7 @@ import (
 
 func main() {
 	fmt.Println("\n\033[1;32m[+] Sub Domain Tool - By SayerLinux\033[0m")
-	fmt.Println("\033[1;32m[+] Email: SaudiSayer@gmail.com\033[0m\n")
+	fmt.Print("\033[1;32m[+] Email: SaudiSayer@gmail.com\033[0m\n\n")
 
 	rootCmd := cmd.NewRootCmd()
 	if err := rootCmd.Execute(); err != nil {
@@ -0,0 +1,267 @@
+package scanner
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/SayerLinux/sub/pkg/utils"
+)
+
+//go:embed data/providers.json
+var defaultProviders []byte
+
+// Provider categories
+const (
+	CategoryCloud = "cloud"
+	CategoryCDN   = "cdn"
+	CategoryWAF   = "waf"
+)
+
+// categoryPriority ranks categories when an address and a CNAME match
+// different providers. Edge services front the cloud they run on.
+var categoryPriority = map[string]int{
+	CategoryWAF:   3,
+	CategoryCDN:   2,
+	CategoryCloud: 1,
+}
+
+// Attribution names the provider hosting an address or name
+type Attribution struct {
+	Provider string `json:"provider"`
+	Category string `json:"category"`
+}
+
+// IsEdge reports whether the attribution is a shared CDN or WAF edge, whose
+// ports and files belong to the provider rather than the target
+func (a *Attribution) IsEdge() bool {
+	return a != nil && (a.Category == CategoryCDN || a.Category == CategoryWAF)
+}
+
+// String returns the attribution as "provider (category)"
+func (a *Attribution) String() string {
+	if a == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s (%s)", a.Provider, a.Category)
+}
+
+// ProviderSignature describes how to recognise a provider. Ranges lists
+// CIDRs, RangeFiles names provider published range files (such as the AWS
+// ip-ranges.json or the Cloudflare ips-v4 list) whose CIDRs are all used,
+// and CNAMEs lists domain suffixes of names pointing at the provider.
+type ProviderSignature struct {
+	Name       string   `json:"name"`
+	Category   string   `json:"category"`
+	Ranges     []string `json:"ranges,omitempty"`
+	RangeFiles []string `json:"range_files,omitempty"`
+	CNAMEs     []string `json:"cnames,omitempty"`
+
+	prefixes []netip.Prefix
+}
+
+var (
+	providers     []ProviderSignature
+	providersOnce sync.Once
+	providersErr  error
+	providersLock sync.RWMutex
+)
+
+// LoadProviderSignatures replaces the built-in provider signatures with the
+// ones from a JSON file using the same format as data/providers.json. Range
+// files are resolved relative to the signature file.
+func LoadProviderSignatures(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read provider file: %v", err)
+	}
+
+	signatures, err := parseProviderSignatures(data, filepath.Dir(path))
+	if err != nil {
+		return fmt.Errorf("invalid provider file %s: %v", path, err)
+	}
+
+	// Make sure the built-in signatures are not loaded over the custom ones later
+	providersOnce.Do(func() {})
+
+	providersLock.Lock()
+	providers = signatures
+	providersErr = nil
+	providersLock.Unlock()
+	return nil
+}
+
+// parseProviderSignatures decodes provider signatures and loads their ranges
+func parseProviderSignatures(data []byte, dir string) ([]ProviderSignature, error) {
+	var signatures []ProviderSignature
+	if err := json.Unmarshal(data, &signatures); err != nil {
+		return nil, err
+	}
+
+	for i := range signatures {
+		sig := &signatures[i]
+		if sig.Name == "" {
+			return nil, fmt.Errorf("provider %d has no name", i)
+		}
+		if _, ok := categoryPriority[sig.Category]; !ok {
+			return nil, fmt.Errorf("%s: unknown category %q (use cloud, cdn or waf)", sig.Name, sig.Category)
+		}
+
+		for _, cidr := range sig.Ranges {
+			prefix, err := netip.ParsePrefix(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", sig.Name, err)
+			}
+			sig.prefixes = append(sig.prefixes, prefix.Masked())
+		}
+
+		for _, file := range sig.RangeFiles {
+			if !filepath.IsAbs(file) {
+				file = filepath.Join(dir, file)
+			}
+			prefixes, err := readRangeFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", sig.Name, err)
+			}
+			sig.prefixes = append(sig.prefixes, prefixes...)
+		}
+
+		for j, suffix := range sig.CNAMEs {
+			sig.CNAMEs[j] = strings.ToLower(strings.Trim(suffix, "."))
+		}
+	}
+
+	return signatures, nil
+}
+
+// readRangeFile extracts every CIDR from a provider range file. JSON files
+// are walked for string values, other files are read as whitespace separated
+// lists, so the files published by most providers can be used unchanged.
+func readRangeFile(path string) ([]netip.Prefix, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read range file: %v", err)
+	}
+
+	var values []string
+	var document interface{}
+	if json.Unmarshal(data, &document) == nil {
+		collectStrings(document, &values)
+	} else {
+		values = strings.Fields(string(data))
+	}
+
+	var prefixes []netip.Prefix
+	for _, value := range values {
+		if prefix, err := netip.ParsePrefix(strings.TrimSpace(value)); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+		}
+	}
+	if len(prefixes) == 0 {
+		return nil, fmt.Errorf("no ranges found in %s", path)
+	}
+	return prefixes, nil
+}
+
+// collectStrings appends every string value of a decoded JSON document
+func collectStrings(value interface{}, values *[]string) {
+	switch v := value.(type) {
+	case string:
+		*values = append(*values, v)
+	case []interface{}:
+		for _, item := range v {
+			collectStrings(item, values)
+		}
+	case map[string]interface{}:
+		for _, item := range v {
+			collectStrings(item, values)
+		}
+	}
+}
+
+// getProviderSignatures returns the active provider signatures, loading the
+// built-in ones on first use
+func getProviderSignatures() ([]ProviderSignature, error) {
+	providersOnce.Do(func() {
+		signatures, err := parseProviderSignatures(defaultProviders, "")
+		providersLock.Lock()
+		providers, providersErr = signatures, err
+		providersLock.Unlock()
+	})
+
+	providersLock.RLock()
+	defer providersLock.RUnlock()
+	return providers, providersErr
+}
+
+// Attribute classifies an address and the CNAMEs of a host as a cloud, CDN
+// or WAF provider. When several providers match, WAFs win over CDNs and CDNs
+// over clouds, then the most specific range. It returns nil if no provider matches.
+func Attribute(ip string, cnames ...string) *Attribution {
+	signatures, err := getProviderSignatures()
+	if err != nil {
+		return nil
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err == nil {
+		addr = addr.Unmap()
+	}
+
+	var (
+		best     *ProviderSignature
+		bestBits = -1
+	)
+	better := func(sig *ProviderSignature, bits int) bool {
+		if best == nil {
+			return true
+		}
+		if categoryPriority[sig.Category] != categoryPriority[best.Category] {
+			return categoryPriority[sig.Category] > categoryPriority[best.Category]
+		}
+		return bits > bestBits
+	}
+
+	for i := range signatures {
+		sig := &signatures[i]
+
+		if addr.IsValid() {
+			for _, prefix := range sig.prefixes {
+				if prefix.Contains(addr) && better(sig, prefix.Bits()) {
+					best, bestBits = sig, prefix.Bits()
+				}
+			}
+		}
+
+		for _, cname := range cnames {
+			cname = strings.ToLower(strings.TrimSuffix(cname, "."))
+			for _, suffix := range sig.CNAMEs {
+				// A CNAME is as specific as the longest possible range
+				if isUnderDomain(cname, suffix) && better(sig, 128) {
+					best, bestBits = sig, 128
+				}
+			}
+		}
+	}
+
+	if best == nil {
+		return nil
+	}
+	return &Attribution{Provider: best.Name, Category: best.Category}
+}
+
+// AttributeHost looks up the CNAME of a host and classifies it along with
+// the address it resolved to
+func AttributeHost(host string, ip string) *Attribution {
+	var cnames []string
+	if cname, err := utils.Resolver().LookupCNAME(context.Background(), host); err == nil && !strings.EqualFold(strings.TrimSuffix(cname, "."), host) {
+		cnames = append(cnames, cname)
+	}
+	return Attribute(ip, cnames...)
+}
@@ -0,0 +1,70 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestAttributeBuiltIn(t *testing.T) {
+	tests := []struct {
+		ip     string
+		cnames []string
+		want   *Attribution
+	}{
+		{"104.16.1.1", nil, &Attribution{Provider: "Cloudflare", Category: CategoryCDN}},
+		{"2606:4700::1", nil, &Attribution{Provider: "Cloudflare", Category: CategoryCDN}},
+		{"45.60.1.1", nil, &Attribution{Provider: "Imperva", Category: CategoryWAF}},
+		{"192.0.2.1", []string{"d111111abcdef8.cloudfront.net."}, &Attribution{Provider: "Amazon CloudFront", Category: CategoryCDN}},
+		{"192.0.2.1", []string{"app.herokuapp.com"}, &Attribution{Provider: "Heroku", Category: CategoryCloud}},
+		// The edge in front wins over the cloud behind it
+		{"185.199.108.153", []string{"site.incapdns.net"}, &Attribution{Provider: "Imperva", Category: CategoryWAF}},
+		{"192.0.2.1", []string{"www.example.com"}, nil},
+		{"not an ip", nil, nil},
+	}
+
+	for _, test := range tests {
+		if got := Attribute(test.ip, test.cnames...); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Attribute(%s, %v) = %v, want %v", test.ip, test.cnames, got, test.want)
+		}
+	}
+
+	if !(&Attribution{Category: CategoryWAF}).IsEdge() || (&Attribution{Category: CategoryCloud}).IsEdge() || (*Attribution)(nil).IsEdge() {
+		t.Error("IsEdge misclassified a category")
+	}
+}
+
+func TestParseProviderSignaturesRangeFiles(t *testing.T) {
+	dir := t.TempDir()
+	aws := `{"syncToken": "1", "prefixes": [{"ip_prefix": "198.51.100.0/24", "region": "eu-west-1", "service": "EC2"}],
+		"ipv6_prefixes": [{"ipv6_prefix": "2001:db8:1::/48", "service": "EC2"}]}`
+	if err := os.WriteFile(filepath.Join(dir, "ip-ranges.json"), []byte(aws), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "edge-v4.txt"), []byte("198.51.100.128/25\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	data := `[
+		{"name": "Example Cloud", "category": "cloud", "range_files": ["ip-ranges.json"]},
+		{"name": "Example Edge", "category": "cdn", "range_files": ["edge-v4.txt"], "cnames": [".edge.example."]}
+	]`
+	signatures, err := parseProviderSignatures([]byte(data), dir)
+	if err != nil {
+		t.Fatalf("parseProviderSignatures failed: %v", err)
+	}
+	if len(signatures[0].prefixes) != 2 || len(signatures[1].prefixes) != 1 {
+		t.Errorf("loaded %d and %d ranges, want 2 and 1", len(signatures[0].prefixes), len(signatures[1].prefixes))
+	}
+	if signatures[1].CNAMEs[0] != "edge.example" {
+		t.Errorf("CNAME suffix = %q, want edge.example", signatures[1].CNAMEs[0])
+	}
+
+	if _, err := parseProviderSignatures([]byte(`[{"name": "X", "category": "hosting"}]`), dir); err == nil {
+		t.Error("unknown category accepted")
+	}
+	if _, err := parseProviderSignatures([]byte(`[{"name": "X", "category": "cdn", "range_files": ["missing.json"]}]`), dir); err == nil {
+		t.Error("missing range file accepted")
+	}
+}
@@ -0,0 +1,88 @@
+package scanner
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// LoadCookieJar loads a cookie file in the Netscape cookies.txt format, as
+// exported by browsers and written by curl -c, into a cookie jar. The jar
+// also keeps the cookies set by the targets during the scan.
+func LoadCookieJar(path string) (http.CookieJar, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cookie file: %v", err)
+	}
+	defer file.Close()
+
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		return nil, err
+	}
+	if err := readCookies(file, jar); err != nil {
+		return nil, fmt.Errorf("invalid cookie file %s: %v", path, err)
+	}
+	return jar, nil
+}
+
+// readCookies adds the cookies of a Netscape cookie file to a jar. Each line
+// holds the domain, whether subdomains match, the path, whether the cookie is
+// secure, its expiry as a Unix time and its name and value, separated by tabs.
+func readCookies(reader io.Reader, jar http.CookieJar) error {
+	scanner := bufio.NewScanner(reader)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		// curl marks HttpOnly cookies with a prefix on an otherwise commented line
+		httpOnly := strings.HasPrefix(line, "#HttpOnly_")
+		line = strings.TrimPrefix(line, "#HttpOnly_")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return fmt.Errorf("line %d: expected 7 tab separated fields, got %d", lineNumber, len(fields))
+		}
+
+		expiry, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid expiry %q", lineNumber, fields[4])
+		}
+
+		host := strings.TrimPrefix(fields[0], ".")
+		cookie := &http.Cookie{
+			Name:     fields[5],
+			Value:    fields[6],
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			HttpOnly: httpOnly,
+		}
+		if strings.EqualFold(fields[1], "TRUE") {
+			cookie.Domain = host
+		}
+		if expiry > 0 {
+			cookie.Expires = time.Unix(expiry, 0)
+		}
+
+		scheme := "http"
+		if cookie.Secure {
+			scheme = "https"
+		}
+		jar.SetCookies(&url.URL{Scheme: scheme, Host: host, Path: cookie.Path}, []*http.Cookie{cookie})
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,395 @@
+package scanner
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SayerLinux/sub/pkg/utils"
+)
+
+// Defaults for reading certificate transparency logs
+const (
+	DefaultCTBatchSize = 256
+	DefaultCTWorkers   = 4
+	DefaultCTBackfill  = 100000
+)
+
+// RFC 6962 log entry types
+const (
+	ctX509Entry    = 0
+	ctPrecertEntry = 1
+)
+
+// SignedTreeHead is the response of the get-sth endpoint
+type SignedTreeHead struct {
+	TreeSize  int64  `json:"tree_size"`
+	Timestamp int64  `json:"timestamp"`
+	RootHash  []byte `json:"sha256_root_hash"`
+	Signature []byte `json:"tree_head_signature"`
+}
+
+// CTLogEntry is an entry returned by the get-entries endpoint
+type CTLogEntry struct {
+	LeafInput []byte `json:"leaf_input"`
+	ExtraData []byte `json:"extra_data"`
+}
+
+// CTClient reads a certificate transparency log through the RFC 6962 API
+type CTClient struct {
+	client *http.Client
+	url    string
+}
+
+// NewCTClient creates a client for the log at logURL, e.g.
+// https://ct.googleapis.com/logs/xenon2024
+func NewCTClient(logURL string, client *http.Client) *CTClient {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second, Transport: utils.NewTransport(false)}
+	}
+	if !strings.Contains(logURL, "://") {
+		logURL = "https://" + logURL
+	}
+	return &CTClient{client: client, url: strings.TrimSuffix(logURL, "/")}
+}
+
+// URL returns the base URL of the log
+func (c *CTClient) URL() string {
+	return c.url
+}
+
+// GetSTH fetches the current signed tree head of the log
+func (c *CTClient) GetSTH(ctx context.Context) (*SignedTreeHead, error) {
+	body, err := fetchSource(ctx, c.client, c.url+"/ct/v1/get-sth", nil)
+	if err != nil {
+		return nil, fmt.Errorf("get-sth failed: %v", err)
+	}
+
+	var sth SignedTreeHead
+	if err := json.Unmarshal(body, &sth); err != nil {
+		return nil, fmt.Errorf("invalid get-sth response: %v", err)
+	}
+	return &sth, nil
+}
+
+// GetEntries fetches the entries from start to end inclusive. Logs may
+// return fewer entries than requested.
+func (c *CTClient) GetEntries(ctx context.Context, start int64, end int64) ([]CTLogEntry, error) {
+	query := fmt.Sprintf("%s/ct/v1/get-entries?start=%d&end=%d", c.url, start, end)
+	body, err := fetchSource(ctx, c.client, query, nil)
+	if err != nil {
+		return nil, fmt.Errorf("get-entries failed: %v", err)
+	}
+
+	var response struct {
+		Entries []CTLogEntry `json:"entries"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("invalid get-entries response: %v", err)
+	}
+	return response.Entries, nil
+}
+
+// ParseCTEntryNames returns the common name and DNS SANs of the certificate
+// or precertificate in a log entry
+func ParseCTEntryNames(entry CTLogEntry) ([]string, error) {
+	// MerkleTreeLeaf: version, leaf type, timestamp and entry type
+	leaf := entry.LeafInput
+	if len(leaf) < 12 {
+		return nil, fmt.Errorf("leaf too short")
+	}
+	if leaf[0] != 0 || leaf[1] != 0 {
+		return nil, fmt.Errorf("unsupported leaf version %d type %d", leaf[0], leaf[1])
+	}
+
+	entryType := binary.BigEndian.Uint16(leaf[10:12])
+	data := leaf[12:]
+
+	var der []byte
+	switch entryType {
+	case ctX509Entry:
+		cert, _, err := readUint24Prefixed(data)
+		if err != nil {
+			return nil, err
+		}
+		der = cert
+	case ctPrecertEntry:
+		// The issuer key hash is followed by the TBSCertificate, which is
+		// wrapped in a certificate structure so the standard parser accepts it
+		if len(data) < 32 {
+			return nil, fmt.Errorf("precert entry too short")
+		}
+		tbs, _, err := readUint24Prefixed(data[32:])
+		if err != nil {
+			return nil, err
+		}
+		algorithm, err := tbsSignatureAlgorithm(tbs)
+		if err != nil {
+			return nil, err
+		}
+		der, err = asn1.Marshal(struct {
+			TBS       asn1.RawValue
+			Algorithm asn1.RawValue
+			Signature asn1.BitString
+		}{
+			TBS:       asn1.RawValue{FullBytes: tbs},
+			Algorithm: asn1.RawValue{FullBytes: algorithm},
+		})
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported entry type %d", entryType)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificate: %v", err)
+	}
+
+	var names []string
+	if cert.Subject.CommonName != "" {
+		names = append(names, cert.Subject.CommonName)
+	}
+	names = append(names, cert.DNSNames...)
+	return names, nil
+}
+
+// tbsSignatureAlgorithm returns the encoded signature algorithm of a
+// TBSCertificate, which the outer certificate structure must repeat
+func tbsSignatureAlgorithm(tbs []byte) ([]byte, error) {
+	var sequence asn1.RawValue
+	if _, err := asn1.Unmarshal(tbs, &sequence); err != nil {
+		return nil, fmt.Errorf("invalid TBSCertificate: %v", err)
+	}
+
+	// Skip the optional explicit version and the serial number
+	var field asn1.RawValue
+	rest, err := asn1.Unmarshal(sequence.Bytes, &field)
+	if err == nil && field.Class == asn1.ClassContextSpecific {
+		rest, err = asn1.Unmarshal(rest, &field)
+	}
+	if err == nil {
+		_, err = asn1.Unmarshal(rest, &field)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid TBSCertificate: %v", err)
+	}
+
+	return field.FullBytes, nil
+}
+
+// readUint24Prefixed reads an opaque value with a 24 bit length prefix
+func readUint24Prefixed(data []byte) ([]byte, []byte, error) {
+	if len(data) < 3 {
+		return nil, nil, fmt.Errorf("truncated length")
+	}
+	length := int(data[0])<<16 | int(data[1])<<8 | int(data[2])
+	if len(data) < 3+length {
+		return nil, nil, fmt.Errorf("truncated value")
+	}
+	return data[3 : 3+length], data[3+length:], nil
+}
+
+// CTCheckpoint records the next entry to read per log and domain, so
+// repeat runs fetch only entries added since the previous run
+type CTCheckpoint struct {
+	path      string
+	mutex     sync.Mutex
+	Positions map[string]int64 `json:"positions"`
+}
+
+// DefaultCTCheckpointPath returns the default location of the log position
+// checkpoint, inside the user configuration directory
+func DefaultCTCheckpointPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "sub", "ct-checkpoint.json")
+}
+
+// LoadCTCheckpoint loads a checkpoint file. A missing file yields an empty
+// checkpoint, and an empty path a checkpoint that is never saved.
+func LoadCTCheckpoint(path string) (*CTCheckpoint, error) {
+	checkpoint := &CTCheckpoint{path: path, Positions: map[string]int64{}}
+	if path == "" {
+		return checkpoint, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return checkpoint, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CT checkpoint: %v", err)
+	}
+
+	if err := json.Unmarshal(data, checkpoint); err != nil {
+		return nil, fmt.Errorf("invalid CT checkpoint %s: %v", path, err)
+	}
+	if checkpoint.Positions == nil {
+		checkpoint.Positions = map[string]int64{}
+	}
+	return checkpoint, nil
+}
+
+// Position returns the next entry to read from a log for a domain
+func (c *CTCheckpoint) Position(logURL string, domain string) (int64, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	position, ok := c.Positions[logURL+" "+domain]
+	return position, ok
+}
+
+// Update records the next entry to read from a log for a domain and saves
+// the checkpoint
+func (c *CTCheckpoint) Update(logURL string, domain string, position int64) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.Positions[logURL+" "+domain] = position
+	if c.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create checkpoint directory: %v", err)
+	}
+	// Write to a temporary file first so an interrupted run keeps the old state
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to save CT checkpoint: %v", err)
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// CTLogSource is a passive source reading the entries of a certificate
+// transparency log added since its checkpoint. The first run for a log reads
+// the last Backfill entries.
+type CTLogSource struct {
+	Client     *CTClient
+	Checkpoint *CTCheckpoint
+	BatchSize  int
+	Workers    int
+	Backfill   int64
+}
+
+// Name returns the source name
+func (s *CTLogSource) Name() string {
+	return "ctlog"
+}
+
+// Enumerate reads the new entries of the log and returns the names under domain
+func (s *CTLogSource) Enumerate(ctx context.Context, domain string) ([]string, error) {
+	sth, err := s.Client.GetSTH(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	start, ok := int64(0), false
+	if s.Checkpoint != nil {
+		start, ok = s.Checkpoint.Position(s.Client.URL(), domain)
+	}
+	if !ok {
+		backfill := s.Backfill
+		if backfill <= 0 {
+			backfill = DefaultCTBackfill
+		}
+		start = max(0, sth.TreeSize-backfill)
+	}
+
+	batchSize := int64(s.BatchSize)
+	if batchSize <= 0 {
+		batchSize = DefaultCTBatchSize
+	}
+	workers := s.Workers
+	if workers <= 0 {
+		workers = DefaultCTWorkers
+	}
+
+	var (
+		mutex sync.Mutex
+		names []string
+	)
+	collect := func(entries []CTLogEntry) {
+		for _, entry := range entries {
+			entryNames, err := ParseCTEntryNames(entry)
+			if err != nil {
+				continue
+			}
+			for _, name := range entryNames {
+				if isUnderDomain(normaliseName(name), domain) {
+					mutex.Lock()
+					names = append(names, name)
+					mutex.Unlock()
+				}
+			}
+		}
+	}
+
+	// Fetch a window of batches concurrently and advance the checkpoint once
+	// the whole window is read, so it never skips an unread entry
+	for start < sth.TreeSize {
+		end := min(sth.TreeSize, start+batchSize*int64(workers))
+
+		var wg sync.WaitGroup
+		errs := make(chan error, workers)
+		for batch := start; batch < end; batch += batchSize {
+			wg.Add(1)
+			go func(first int64, last int64) {
+				defer wg.Done()
+				if err := s.readRange(ctx, first, last, collect); err != nil {
+					errs <- err
+				}
+			}(batch, min(end, batch+batchSize)-1)
+		}
+		wg.Wait()
+		close(errs)
+
+		if err := <-errs; err != nil {
+			return names, err
+		}
+
+		start = end
+		if s.Checkpoint != nil {
+			if err := s.Checkpoint.Update(s.Client.URL(), domain, start); err != nil {
+				return names, err
+			}
+		}
+	}
+
+	return names, nil
+}
+
+// readRange fetches the entries from first to last inclusive, repeating the
+// request when the log returns a partial batch
+func (s *CTLogSource) readRange(ctx context.Context, first int64, last int64, collect func([]CTLogEntry)) error {
+	for first <= last {
+		entries, err := s.Client.GetEntries(ctx, first, last)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return fmt.Errorf("log returned no entries for %d-%d", first, last)
+		}
+
+		collect(entries)
+		first += int64(len(entries))
+	}
+	return nil
+}
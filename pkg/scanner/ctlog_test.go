@@ -0,0 +1,200 @@
+package scanner
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeCTLog is a stand-in RFC 6962 log serving a fixed list of leaves and
+// returning at most maxBatch entries per request
+type fakeCTLog struct {
+	mutex    sync.Mutex
+	leaves   [][]byte
+	maxBatch int
+	fetched  []int64
+}
+
+func (l *fakeCTLog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	switch r.URL.Path {
+	case "/log/ct/v1/get-sth":
+		json.NewEncoder(w).Encode(SignedTreeHead{TreeSize: int64(len(l.leaves)), Timestamp: time.Now().UnixMilli()})
+	case "/log/ct/v1/get-entries":
+		start, _ := strconv.ParseInt(r.URL.Query().Get("start"), 10, 64)
+		end, _ := strconv.ParseInt(r.URL.Query().Get("end"), 10, 64)
+		if start < 0 || end < start || end >= int64(len(l.leaves)) {
+			http.Error(w, "bad range", http.StatusBadRequest)
+			return
+		}
+		end = min(end, start+int64(l.maxBatch)-1)
+
+		var response struct {
+			Entries []CTLogEntry `json:"entries"`
+		}
+		for i := start; i <= end; i++ {
+			l.fetched = append(l.fetched, i)
+			response.Entries = append(response.Entries, CTLogEntry{LeafInput: l.leaves[i]})
+		}
+		json.NewEncoder(w).Encode(response)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// newTestCertificate creates a certificate for the names, returning the DER
+// of the certificate and of its TBSCertificate
+func newTestCertificate(t *testing.T, commonName string, names ...string) ([]byte, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     names,
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der, cert.RawTBSCertificate
+}
+
+// ctLeaf encodes a MerkleTreeLeaf holding an x509 or precert entry
+func ctLeaf(entryType uint16, data []byte) []byte {
+	leaf := []byte{0, 0}
+	leaf = binary.BigEndian.AppendUint64(leaf, uint64(time.Now().UnixMilli()))
+	leaf = binary.BigEndian.AppendUint16(leaf, entryType)
+	if entryType == ctPrecertEntry {
+		issuerKeyHash := sha256.Sum256([]byte("issuer"))
+		leaf = append(leaf, issuerKeyHash[:]...)
+	}
+	leaf = append(leaf, byte(len(data)>>16), byte(len(data)>>8), byte(len(data)))
+	leaf = append(leaf, data...)
+	// No extensions
+	return append(leaf, 0, 0)
+}
+
+func TestParseCTEntryNames(t *testing.T) {
+	cert, _ := newTestCertificate(t, "www.example.com", "www.example.com", "mail.example.com")
+	names, err := ParseCTEntryNames(CTLogEntry{LeafInput: ctLeaf(ctX509Entry, cert)})
+	if err != nil {
+		t.Fatalf("x509 entry failed: %v", err)
+	}
+	if want := []string{"www.example.com", "www.example.com", "mail.example.com"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("x509 names = %v, want %v", names, want)
+	}
+
+	_, tbs := newTestCertificate(t, "", "*.dev.example.com")
+	names, err = ParseCTEntryNames(CTLogEntry{LeafInput: ctLeaf(ctPrecertEntry, tbs)})
+	if err != nil {
+		t.Fatalf("precert entry failed: %v", err)
+	}
+	if want := []string{"*.dev.example.com"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("precert names = %v, want %v", names, want)
+	}
+
+	if _, err := ParseCTEntryNames(CTLogEntry{LeafInput: []byte{0, 0, 1}}); err == nil {
+		t.Error("truncated leaf accepted")
+	}
+}
+
+func TestCTLogSourceCheckpoint(t *testing.T) {
+	log := &fakeCTLog{maxBatch: 3}
+	for i, names := range [][]string{
+		{"a.example.com"},
+		{"b.example.org"},
+		{"c.example.com", "d.example.com"},
+		{"e.other.net"},
+		{"f.example.com"},
+	} {
+		cert, tbs := newTestCertificate(t, names[0], names...)
+		if i%2 == 0 {
+			log.leaves = append(log.leaves, ctLeaf(ctX509Entry, cert))
+		} else {
+			log.leaves = append(log.leaves, ctLeaf(ctPrecertEntry, tbs))
+		}
+	}
+
+	server := httptest.NewServer(log)
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	checkpoint, err := LoadCTCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source := &CTLogSource{
+		Client:     NewCTClient(server.URL+"/log/", server.Client()),
+		Checkpoint: checkpoint,
+		BatchSize:  4,
+		Workers:    2,
+	}
+
+	results, errs := EnumerateSources(context.Background(), "example.com", []Source{source})
+	if len(errs) > 0 {
+		t.Fatalf("first run failed: %v", errs)
+	}
+	var names []string
+	for _, result := range results {
+		names = append(names, result.Subdomain)
+	}
+	if want := []string{"a.example.com", "c.example.com", "d.example.com", "f.example.com"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("first run names = %v, want %v", names, want)
+	}
+
+	// A new entry is added, the second run reads only that entry
+	cert, _ := newTestCertificate(t, "g.example.com")
+	log.leaves = append(log.leaves, ctLeaf(ctX509Entry, cert))
+	log.fetched = nil
+
+	checkpoint, err = LoadCTCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source.Checkpoint = checkpoint
+
+	found, err := source.Enumerate(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("second run failed: %v", err)
+	}
+	if want := []string{"g.example.com"}; !reflect.DeepEqual(found, want) {
+		t.Errorf("second run names = %v, want %v", found, want)
+	}
+	sort.Slice(log.fetched, func(i, j int) bool { return log.fetched[i] < log.fetched[j] })
+	if want := []int64{5}; !reflect.DeepEqual(log.fetched, want) {
+		t.Errorf("second run fetched entries %v, want %v", log.fetched, want)
+	}
+
+	if position, _ := checkpoint.Position(source.Client.URL(), "example.com"); position != 6 {
+		t.Errorf("checkpoint position = %d, want 6", position)
+	}
+}
@@ -0,0 +1,286 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxDatasetLineLength is the longest dataset line parsed, longer lines are skipped
+const maxDatasetLineLength = 1 << 20
+
+// Dataset formats understood by ReadDataset
+const (
+	DatasetFDNS = "fdns"
+	DatasetZone = "zone"
+)
+
+// DatasetRecord is a name found in a DNS dataset with the address it pointed
+// to, if the record was an address record
+type DatasetRecord struct {
+	Name string
+	IP   string
+}
+
+// ReadDataset streams a forward-DNS JSON dump or a BIND zone file, gzipped or
+// not, and calls emit for every name under domain. Records are handled one
+// line at a time so memory use does not grow with the size of the file.
+// The format is detected from the file name and content unless given.
+func ReadDataset(path string, format string, domain string, emit func(DatasetRecord)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open dataset: %v", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReaderSize(file, maxDatasetLineLength)
+
+	// Gzip is detected from the magic number rather than the extension
+	if magic, _ := reader.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return fmt.Errorf("failed to open gzip dataset: %v", err)
+		}
+		defer gz.Close()
+		reader = bufio.NewReaderSize(gz, maxDatasetLineLength)
+	}
+
+	if format == "" {
+		format = detectDatasetFormat(path, reader)
+	}
+
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	switch format {
+	case DatasetFDNS:
+		err = readFDNS(reader, domain, emit)
+	case DatasetZone:
+		err = readZone(reader, domain, emit)
+	default:
+		return fmt.Errorf("unknown dataset format %q (use %s or %s)", format, DatasetFDNS, DatasetZone)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	return nil
+}
+
+// detectDatasetFormat guesses the format of a dataset from its extension,
+// falling back to JSON detection on the first bytes
+func detectDatasetFormat(path string, reader *bufio.Reader) string {
+	name := strings.TrimSuffix(strings.ToLower(filepath.Base(path)), ".gz")
+	switch filepath.Ext(name) {
+	case ".json", ".jsonl", ".ndjson":
+		return DatasetFDNS
+	case ".zone", ".db", ".txt":
+		return DatasetZone
+	}
+
+	if head, _ := reader.Peek(512); bytes.HasPrefix(bytes.TrimSpace(head), []byte("{")) {
+		return DatasetFDNS
+	}
+	return DatasetZone
+}
+
+// readLines calls fn for every line of the reader, skipping lines longer
+// than maxDatasetLineLength instead of buffering them
+func readLines(reader *bufio.Reader, fn func(line []byte)) error {
+	var long bool
+	for {
+		line, err := reader.ReadSlice('\n')
+		switch {
+		case err == bufio.ErrBufferFull:
+			long = true
+			continue
+		case long:
+			long = false
+		case len(line) > 0:
+			fn(line)
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil && err != bufio.ErrBufferFull {
+			return err
+		}
+	}
+}
+
+// readFDNS parses a forward-DNS dump with one JSON record per line, as
+// published by Project Sonar: {"name": ..., "type": ..., "value": ...}
+func readFDNS(reader *bufio.Reader, domain string, emit func(DatasetRecord)) error {
+	needle := []byte(domain)
+
+	return readLines(reader, func(line []byte) {
+		// Most lines of a full dump are for other domains, skip them
+		// before paying for the JSON decoding
+		if !bytes.Contains(bytes.ToLower(line), needle) {
+			return
+		}
+
+		var record struct {
+			Name  string `json:"name"`
+			Type  string `json:"type"`
+			Value string `json:"value"`
+		}
+		if err := json.Unmarshal(line, &record); err != nil {
+			return
+		}
+
+		emitRecord(domain, record.Name, record.Type, record.Value, emit)
+	})
+}
+
+// readZone parses a BIND zone file. $ORIGIN, relative and omitted owner
+// names and multi-line records in parentheses are supported.
+func readZone(reader *bufio.Reader, domain string, emit func(DatasetRecord)) error {
+	origin := domain
+	owner := ""
+
+	var pending []string
+	depth := 0
+
+	return readLines(reader, func(raw []byte) {
+		line := string(raw)
+		if i := zoneComment(line); i >= 0 {
+			line = line[:i]
+		}
+
+		// An owner is omitted when the line starts with white space
+		continued := depth > 0
+		blankOwner := !continued && len(line) > 0 && (line[0] == ' ' || line[0] == '\t')
+
+		depth += strings.Count(line, "(") - strings.Count(line, ")")
+		fields := strings.Fields(strings.NewReplacer("(", " ", ")", " ").Replace(line))
+		if continued {
+			pending = append(pending, fields...)
+		} else {
+			pending = fields
+			if blankOwner {
+				pending = append([]string{""}, pending...)
+			}
+		}
+		if depth > 0 || len(pending) == 0 {
+			return
+		}
+
+		fields, pending = pending, nil
+		switch strings.ToUpper(fields[0]) {
+		case "$ORIGIN":
+			if len(fields) > 1 {
+				origin = absoluteName(fields[1], origin)
+			}
+			return
+		case "$TTL", "$INCLUDE", "$GENERATE":
+			return
+		}
+
+		if fields[0] != "" {
+			owner = absoluteName(fields[0], origin)
+		}
+
+		// Skip the optional TTL and class before the record type
+		rest := fields[1:]
+		for len(rest) > 0 && (isZoneTTL(rest[0]) || isZoneClass(rest[0])) {
+			rest = rest[1:]
+		}
+		if len(rest) == 0 {
+			return
+		}
+
+		recordType := strings.ToLower(rest[0])
+		value := ""
+		if len(rest) > 1 {
+			value = rest[len(rest)-1]
+			if recordType != "a" && recordType != "aaaa" {
+				value = absoluteName(value, origin)
+			}
+		}
+
+		emitRecord(domain, owner, recordType, value, emit)
+	})
+}
+
+// emitRecord emits the owner of a record if it is under domain, along with
+// the address of address records and the target of records pointing at
+// other names under domain
+func emitRecord(domain string, name string, recordType string, value string, emit func(DatasetRecord)) {
+	name = normaliseName(name)
+	recordType = strings.ToLower(recordType)
+
+	if isUnderDomain(name, domain) {
+		record := DatasetRecord{Name: name}
+		if (recordType == "a" || recordType == "aaaa") && net.ParseIP(value) != nil {
+			record.IP = value
+		}
+		emit(record)
+	}
+
+	switch recordType {
+	case "cname", "ns", "mx", "srv", "ptr":
+		if target := normaliseName(value); isUnderDomain(target, domain) {
+			emit(DatasetRecord{Name: target})
+		}
+	}
+}
+
+// absoluteName resolves a zone file name relative to the origin
+func absoluteName(name string, origin string) string {
+	switch {
+	case name == "@":
+		return origin
+	case strings.HasSuffix(name, "."):
+		return strings.TrimSuffix(name, ".")
+	case origin == "":
+		return name
+	default:
+		return name + "." + origin
+	}
+}
+
+// zoneComment returns the index of the comment in a zone file line, ignoring
+// semicolons in quoted strings, or -1
+func zoneComment(line string) int {
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '"':
+			quoted = !quoted
+		case ';':
+			if !quoted {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// isZoneTTL reports whether a zone file field is a TTL such as 3600 or 1h30m
+func isZoneTTL(field string) bool {
+	if field == "" || field[0] < '0' || field[0] > '9' {
+		return false
+	}
+	for _, c := range strings.ToLower(field) {
+		if (c < '0' || c > '9') && !strings.ContainsRune("smhdw", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// isZoneClass reports whether a zone file field is a DNS class
+func isZoneClass(field string) bool {
+	switch strings.ToUpper(field) {
+	case "IN", "CH", "HS", "CS":
+		return true
+	}
+	return false
+}
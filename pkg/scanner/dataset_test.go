@@ -0,0 +1,86 @@
+package scanner
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func readTestDataset(t *testing.T, path string, domain string) []DatasetRecord {
+	t.Helper()
+
+	var records []DatasetRecord
+	err := ReadDataset(path, "", domain, func(record DatasetRecord) {
+		records = append(records, record)
+	})
+	if err != nil {
+		t.Fatalf("ReadDataset failed: %v", err)
+	}
+	return records
+}
+
+func TestReadDatasetZone(t *testing.T) {
+	zone := `$ORIGIN example.com.
+$TTL 3600
+@   IN SOA ns1 hostmaster (
+        2024010101 ; serial
+        3600 900 604800 86400 )
+    IN NS ns1.example.com.
+ns1 IN A 192.0.2.1
+WWW 300 IN CNAME app
+app IN A 192.0.2.2
+    IN AAAA 2001:db8::2
+txt IN TXT "v=spf1; -all"
+$ORIGIN dev.example.com.
+api IN A 192.0.2.3
+other.org. IN A 192.0.2.4
+`
+	path := filepath.Join(t.TempDir(), "example.com.zone")
+	if err := os.WriteFile(path, []byte(zone), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []DatasetRecord{
+		{Name: "example.com"},
+		{Name: "example.com"},
+		{Name: "ns1.example.com"},
+		{Name: "ns1.example.com", IP: "192.0.2.1"},
+		{Name: "www.example.com"},
+		{Name: "app.example.com"},
+		{Name: "app.example.com", IP: "192.0.2.2"},
+		{Name: "app.example.com", IP: "2001:db8::2"},
+		{Name: "txt.example.com"},
+		{Name: "api.dev.example.com", IP: "192.0.2.3"},
+	}
+	if got := readTestDataset(t, path, "example.com"); !reflect.DeepEqual(got, want) {
+		t.Errorf("records = %v, want %v", got, want)
+	}
+}
+
+func TestReadDatasetFDNSGzip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fdns.json.gz")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(file)
+	gz.Write([]byte(`{"timestamp":"1","name":"www.example.com","type":"a","value":"192.0.2.1"}
+{"timestamp":"1","name":"www.example.org","type":"a","value":"192.0.2.9"}
+{"timestamp":"1","name":"cdn.example.com","type":"cname","value":"edge.example.com"}
+not json mentioning example.com
+{"timestamp":"1","name":"notexample.com","type":"a","value":"192.0.2.8"}
+`))
+	gz.Close()
+	file.Close()
+
+	want := []DatasetRecord{
+		{Name: "www.example.com", IP: "192.0.2.1"},
+		{Name: "cdn.example.com"},
+		{Name: "edge.example.com"},
+	}
+	if got := readTestDataset(t, path, "example.com"); !reflect.DeepEqual(got, want) {
+		t.Errorf("records = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,299 @@
+package scanner
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// IPInfo describes the network an address belongs to
+type IPInfo struct {
+	ASN          uint   `json:"asn,omitempty"`
+	Organisation string `json:"organisation,omitempty"`
+	Country      string `json:"country,omitempty"`
+}
+
+// Owner returns a display name for the network, e.g. "AS13335 Cloudflare (US)"
+func (info *IPInfo) Owner() string {
+	if info == nil || info.ASN == 0 && info.Organisation == "" {
+		return "Unknown"
+	}
+
+	owner := info.Organisation
+	if info.ASN != 0 {
+		owner = strings.TrimSpace(fmt.Sprintf("AS%d %s", info.ASN, owner))
+	}
+	if info.Country != "" {
+		owner += " (" + info.Country + ")"
+	}
+	return owner
+}
+
+// ipRange is a range of addresses from an iptoasn table
+type ipRange struct {
+	start netip.Addr
+	end   netip.Addr
+	info  IPInfo
+}
+
+// ipTable is an iptoasn table sorted by range start
+type ipTable []ipRange
+
+// mmdbTable is an open MMDB file
+type mmdbTable struct {
+	reader *maxminddb.Reader
+}
+
+// ipLookup is a database file mapping addresses to networks
+type ipLookup interface {
+	lookup(addr netip.Addr) IPInfo
+}
+
+// IPDatabase maps addresses to their network using local iptoasn TSV tables
+// and MMDB files. Later files fill in the fields earlier files leave empty,
+// so an ASN database can be combined with a country database.
+type IPDatabase struct {
+	tables []ipLookup
+}
+
+// mmdbRecord holds the fields read from MMDB files, covering the MaxMind
+// GeoLite2 ASN and Country layouts and the ipinfo layout
+type mmdbRecord struct {
+	ASN          uint   `maxminddb:"autonomous_system_number"`
+	Organisation string `maxminddb:"autonomous_system_organization"`
+	Country      struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	IPInfoASN     string `maxminddb:"asn"`
+	IPInfoName    string `maxminddb:"as_name"`
+	IPInfoCountry string `maxminddb:"country_code"`
+}
+
+// LoadIPDatabase loads iptoasn TSV tables (optionally gzipped) and MMDB
+// files. The format of each file is detected from its content.
+func LoadIPDatabase(paths ...string) (*IPDatabase, error) {
+	db := &IPDatabase{}
+	for _, path := range paths {
+		if err := db.load(path); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+// load adds a database file
+func (db *IPDatabase) load(path string) error {
+	if reader, err := maxminddb.Open(path); err == nil {
+		db.tables = append(db.tables, mmdbTable{reader: reader})
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open IP database: %v", err)
+	}
+	defer file.Close()
+
+	buffered := bufio.NewReader(file)
+	var reader io.Reader = buffered
+	if magic, _ := buffered.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return fmt.Errorf("failed to open IP database %s: %v", path, err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	ranges, err := readIPToASN(reader)
+	if err != nil {
+		return fmt.Errorf("invalid IP database %s: %v", path, err)
+	}
+	db.tables = append(db.tables, ranges)
+	return nil
+}
+
+// readIPToASN parses an iptoasn table with the columns range start, range
+// end, AS number, country code and AS description
+func readIPToASN(reader io.Reader) (ipTable, error) {
+	var ranges ipTable
+
+	scanner := bufio.NewScanner(reader)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) < 5 {
+			return nil, fmt.Errorf("line %d: expected 5 columns, got %d", lineNumber, len(fields))
+		}
+
+		start, err1 := netip.ParseAddr(fields[0])
+		end, err2 := netip.ParseAddr(fields[1])
+		asn, err3 := strconv.ParseUint(fields[2], 10, 32)
+		if err1 != nil || err2 != nil || err3 != nil {
+			return nil, fmt.Errorf("line %d: invalid range", lineNumber)
+		}
+
+		// AS 0 marks address space that is not routed
+		if asn == 0 {
+			continue
+		}
+
+		country := fields[3]
+		if country == "None" {
+			country = ""
+		}
+
+		ranges = append(ranges, ipRange{
+			start: start.Unmap(),
+			end:   end.Unmap(),
+			info:  IPInfo{ASN: uint(asn), Organisation: fields[4], Country: country},
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].start.Less(ranges[j].start)
+	})
+	return ranges, nil
+}
+
+// Lookup returns the network of an address, or nil if no database knows it
+func (db *IPDatabase) Lookup(address string) *IPInfo {
+	if db == nil {
+		return nil
+	}
+
+	addr, err := netip.ParseAddr(address)
+	if err != nil {
+		return nil
+	}
+	addr = addr.Unmap()
+
+	var info IPInfo
+	for _, table := range db.tables {
+		info.merge(table.lookup(addr))
+	}
+
+	if info == (IPInfo{}) {
+		return nil
+	}
+	return &info
+}
+
+// lookup finds the range holding an address
+func (ranges ipTable) lookup(addr netip.Addr) IPInfo {
+	// Find the last range starting at or before the address
+	i := sort.Search(len(ranges), func(i int) bool {
+		return addr.Less(ranges[i].start)
+	}) - 1
+	if i >= 0 && addr.BitLen() == ranges[i].end.BitLen() && !ranges[i].end.Less(addr) {
+		return ranges[i].info
+	}
+	return IPInfo{}
+}
+
+// lookup reads the record of an address from the MMDB file
+func (table mmdbTable) lookup(addr netip.Addr) IPInfo {
+	var record mmdbRecord
+	if err := table.reader.Lookup(net.IP(addr.AsSlice()), &record); err != nil {
+		return IPInfo{}
+	}
+
+	info := IPInfo{ASN: record.ASN, Organisation: record.Organisation, Country: record.Country.ISOCode}
+	if info.ASN == 0 {
+		asn, _ := strconv.ParseUint(strings.TrimPrefix(record.IPInfoASN, "AS"), 10, 32)
+		info.ASN = uint(asn)
+	}
+	if info.Organisation == "" {
+		info.Organisation = record.IPInfoName
+	}
+	if info.Country == "" {
+		info.Country = record.IPInfoCountry
+	}
+	return info
+}
+
+// merge fills the empty fields of info from other
+func (info *IPInfo) merge(other IPInfo) {
+	if info.ASN == 0 {
+		info.ASN = other.ASN
+	}
+	if info.Organisation == "" {
+		info.Organisation = other.Organisation
+	}
+	if info.Country == "" {
+		info.Country = other.Country
+	}
+}
+
+// Close releases the MMDB files of the database
+func (db *IPDatabase) Close() {
+	if db == nil {
+		return
+	}
+	for _, table := range db.tables {
+		if mmdb, ok := table.(mmdbTable); ok {
+			mmdb.reader.Close()
+		}
+	}
+	db.tables = nil
+}
+
+// NetworkGroup is a network owner with the hosts found in its address space
+type NetworkGroup struct {
+	Owner string
+	Hosts []string
+}
+
+// GroupByNetwork groups hosts by the owner of the network they resolve to,
+// largest group first. networks maps each host to its network information.
+func GroupByNetwork(networks map[string]*IPInfo) []NetworkGroup {
+	hosts := make(map[string][]string)
+	for host, info := range networks {
+		owner := info.Owner()
+		hosts[owner] = append(hosts[owner], host)
+	}
+
+	groups := make([]NetworkGroup, 0, len(hosts))
+	for owner, members := range hosts {
+		sort.Strings(members)
+		groups = append(groups, NetworkGroup{Owner: owner, Hosts: members})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if len(groups[i].Hosts) != len(groups[j].Hosts) {
+			return len(groups[i].Hosts) > len(groups[j].Hosts)
+		}
+		return groups[i].Owner < groups[j].Owner
+	})
+	return groups
+}
+
+// FormatNetworkGroups renders network groups as summary lines
+func FormatNetworkGroups(groups []NetworkGroup) string {
+	var sb strings.Builder
+	for _, group := range groups {
+		sb.WriteString(fmt.Sprintf("  %s: %d hosts\n", group.Owner, len(group.Hosts)))
+		for _, host := range group.Hosts {
+			sb.WriteString(fmt.Sprintf("    - %s\n", host))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,71 @@
+package scanner
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIPDatabaseIPToASN(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ip2asn-combined.tsv.gz")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(file)
+	gz.Write([]byte("1.0.0.0\t1.0.0.255\t13335\tUS\tCLOUDFLARENET\n" +
+		"1.0.1.0\t1.0.3.255\t0\tNone\tNot routed\n" +
+		"192.0.2.0\t192.0.2.255\t64500\tNone\tDOCUMENTATION\n" +
+		"2001:db8::\t2001:db8:ffff:ffff:ffff:ffff:ffff:ffff\t64501\tNL\tDOC-V6\n"))
+	gz.Close()
+	file.Close()
+
+	db, err := LoadIPDatabase(path)
+	if err != nil {
+		t.Fatalf("LoadIPDatabase failed: %v", err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		ip   string
+		want *IPInfo
+	}{
+		{"1.0.0.1", &IPInfo{ASN: 13335, Organisation: "CLOUDFLARENET", Country: "US"}},
+		{"::ffff:1.0.0.200", &IPInfo{ASN: 13335, Organisation: "CLOUDFLARENET", Country: "US"}},
+		{"1.0.2.1", nil},
+		{"192.0.2.77", &IPInfo{ASN: 64500, Organisation: "DOCUMENTATION"}},
+		{"2001:db8::1", &IPInfo{ASN: 64501, Organisation: "DOC-V6", Country: "NL"}},
+		{"8.8.8.8", nil},
+		{"invalid", nil},
+	}
+	for _, test := range tests {
+		if got := db.Lookup(test.ip); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Lookup(%s) = %v, want %v", test.ip, got, test.want)
+		}
+	}
+
+	if _, err := LoadIPDatabase(filepath.Join(t.TempDir(), "missing.tsv")); err == nil {
+		t.Error("missing database accepted")
+	}
+}
+
+func TestGroupByNetwork(t *testing.T) {
+	cloudflare := &IPInfo{ASN: 13335, Organisation: "CLOUDFLARENET", Country: "US"}
+	groups := GroupByNetwork(map[string]*IPInfo{
+		"www.example.com":  cloudflare,
+		"api.example.com":  cloudflare,
+		"mail.example.com": {ASN: 64500, Organisation: "EXAMPLE-HOSTING"},
+		"vpn.example.com":  nil,
+	})
+
+	want := []NetworkGroup{
+		{Owner: "AS13335 CLOUDFLARENET (US)", Hosts: []string{"api.example.com", "www.example.com"}},
+		{Owner: "AS64500 EXAMPLE-HOSTING", Hosts: []string{"mail.example.com"}},
+		{Owner: "Unknown", Hosts: []string{"vpn.example.com"}},
+	}
+	if !reflect.DeepEqual(groups, want) {
+		t.Errorf("groups = %v, want %v", groups, want)
+	}
+}
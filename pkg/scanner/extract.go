@@ -0,0 +1,382 @@
+package scanner
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	pathpkg "path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+)
+
+// maxDownloadSize is the maximum size of a file saved by ExtractFiles
+const maxDownloadSize = 10 << 20
+
+// DefaultExtractPaths is the list of paths checked when no path wordlist is given
+var DefaultExtractPaths = []string{
+	"/robots.txt",
+	"/sitemap.xml",
+	"/.git/HEAD",
+	"/.git/config",
+	"/.env",
+	"/.htaccess",
+	"/.DS_Store",
+	"/wp-config.php",
+	"/config.php",
+	"/composer.json",
+	"/package.json",
+	"/backup.zip",
+	"/backup.sql",
+	"/dump.sql",
+	"/admin/",
+	"/backup/",
+	"/database/",
+	"/api/",
+}
+
+// ExtractConfig holds the file extraction configuration
+type ExtractConfig struct {
+	OutputDir string
+	Paths     []string
+	Threads   int
+	Results   *ResultManager
+	DumpGit   bool
+	GitDump   GitDumpConfig
+
+	// ScanSecrets runs the secret rules over every downloaded file,
+	// RevealSecrets reports the secret values unmasked
+	ScanSecrets   bool
+	RevealSecrets bool
+}
+
+// httpResponse holds the parts of a response used to decide whether a file exists
+type httpResponse struct {
+	StatusCode int
+	Location   string
+	Body       []byte
+	Hash       string
+}
+
+// ExtractFiles attempts to extract files from a subdomain. Every candidate is
+// compared with the response to a random path to weed out soft-404 pages and
+// validated against the content expected for its file type before it is saved.
+func ExtractFiles(subdomain string, config ExtractConfig) error {
+	if !HostInScope(subdomain) {
+		return fmt.Errorf("%s is out of scope", subdomain)
+	}
+
+	paths := config.Paths
+	if len(paths) == 0 {
+		paths = DefaultExtractPaths
+	}
+
+	// Create output directory for this subdomain
+	subdomainDir := filepath.Join(config.OutputDir, subdomain)
+	err := os.MkdirAll(subdomainDir, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create output directory: %v", err)
+	}
+
+	// Baseline responses for a path that cannot exist, per scheme
+	baselines := map[string]*httpResponse{
+		"http":  fetchBaseline("http", subdomain),
+		"https": fetchBaseline("https", subdomain),
+	}
+
+	threads := config.Threads
+	if threads < 1 {
+		threads = 1
+	}
+
+	// Files saved by this run, for the secret scanner
+	var (
+		saved     []string
+		savedMu   sync.Mutex
+		wg        sync.WaitGroup
+		semaphore = make(chan struct{}, threads)
+	)
+
+	// Check each path
+	for _, path := range paths {
+		// Keep requests and saved files below the web and output roots
+		isDir := strings.HasSuffix(path, "/")
+		path = pathpkg.Clean("/" + path)
+		if isDir && path != "/" {
+			path += "/"
+		}
+
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(path string) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			if filePath, ok := extractPath(subdomain, subdomainDir, path, baselines, config); ok {
+				savedMu.Lock()
+				saved = append(saved, filePath)
+				savedMu.Unlock()
+			}
+		}(path)
+	}
+	wg.Wait()
+
+	if config.ScanSecrets {
+		scanSecrets(subdomain, saved, config)
+	}
+
+	return nil
+}
+
+// scanSecrets runs the secret scanner over downloaded files and records the findings
+func scanSecrets(subdomain string, files []string, config ExtractConfig) {
+	for _, file := range files {
+		findings, err := ScanSecrets(file, config.RevealSecrets)
+		if err != nil {
+			fmt.Printf("%s %v\n", color.RedString("[!]"), err)
+			continue
+		}
+
+		for _, finding := range findings {
+			if config.Results != nil {
+				config.Results.AddSecretResult(subdomain, finding)
+			} else {
+				fmt.Printf("%s %s:%d - %s - %s\n", color.RedString("[SECRET]"), finding.File, finding.Line, finding.RuleID, finding.Secret)
+			}
+		}
+	}
+}
+
+// extractPath checks a single path over HTTP, then HTTPS, and saves the file
+// if it is found. It returns the local path of the saved file.
+func extractPath(subdomain string, subdomainDir string, path string, baselines map[string]*httpResponse, config ExtractConfig) (string, bool) {
+	for _, scheme := range []string{"http", "https"} {
+		url := fmt.Sprintf("%s://%s%s", scheme, subdomain, path)
+		resp, err := fetchURL(url)
+		if err != nil || !isFileFound(path, resp, baselines[scheme]) {
+			continue
+		}
+
+		filePath, err := saveFile(subdomainDir, path, resp.Body)
+		if err != nil {
+			continue
+		}
+
+		if config.Results != nil {
+			config.Results.AddFileResult(subdomain, url, filePath, true, int64(len(resp.Body)), resp.Hash)
+		} else {
+			fmt.Printf("%s Found: %s\n", color.GreenString("[+]"), url)
+		}
+
+		// An exposed HEAD usually means the whole repository can be rebuilt
+		if config.DumpGit && strings.HasSuffix(path, "/.git/HEAD") {
+			dumpGit(subdomain, strings.TrimSuffix(url, "HEAD"), filepath.Dir(filepath.Dir(filePath)), config)
+		}
+		return filePath, true
+	}
+
+	return "", false
+}
+
+// dumpGit reconstructs an exposed repository next to the extracted files and
+// records it as a file result
+func dumpGit(subdomain string, baseURL string, outputDir string, config ExtractConfig) {
+	dump, err := DumpGitRepository(baseURL, outputDir, config.GitDump)
+	if err != nil {
+		fmt.Printf("%s Failed to reconstruct %s: %v\n", color.RedString("[!]"), baseURL, err)
+		return
+	}
+
+	if config.Results != nil {
+		config.Results.AddFileResult(subdomain, baseURL, filepath.Join(dump.Directory, ".git"), true, dump.Size, "")
+	}
+}
+
+// fetchBaseline requests a random path to learn how the host answers for
+// files that do not exist. It returns nil if the host cannot be reached.
+func fetchBaseline(scheme string, subdomain string) *httpResponse {
+	token := make([]byte, 12)
+	if _, err := rand.Read(token); err != nil {
+		return nil
+	}
+
+	url := fmt.Sprintf("%s://%s/%s", scheme, subdomain, hex.EncodeToString(token))
+	resp, err := fetchURL(url)
+	if err != nil {
+		return nil
+	}
+	resp.Body = bytes.ReplaceAll(resp.Body, []byte(hex.EncodeToString(token)), nil)
+	return resp
+}
+
+// fetchURL requests a URL without following redirects and reads its body
+func fetchURL(url string) (*httpResponse, error) {
+	return fetchLimited(url, maxDownloadSize)
+}
+
+// fetchLimited requests a URL without following redirects and reads up to limit bytes of its body
+func fetchLimited(url string, limit int64) (*httpResponse, error) {
+	return fetchWith(httpClient(), url, limit)
+}
+
+// fetchWith performs a GET request with the client and reads up to limit
+// bytes of the body
+func fetchWith(client *http.Client, url string, limit int64) (*httpResponse, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit))
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(body)
+	return &httpResponse{
+		StatusCode: resp.StatusCode,
+		Location:   resp.Header.Get("Location"),
+		Body:       body,
+		Hash:       hex.EncodeToString(sum[:]),
+	}, nil
+}
+
+// isFileFound decides whether a response is a real hit for the path
+func isFileFound(path string, resp *httpResponse, baseline *httpResponse) bool {
+	// Redirects (typically to a login page) and errors are never a hit
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 || len(resp.Body) == 0 {
+		return false
+	}
+
+	// A file type with a validator is found when its content checks out
+	if validator := findValidator(path); validator != nil {
+		return validator(resp.Body) && (baseline == nil || resp.Hash != baseline.Hash)
+	}
+
+	return baseline == nil || !isSoftNotFound(path, resp, baseline)
+}
+
+// isSoftNotFound reports whether a response looks like the host's answer for a
+// random path, i.e. a "not found" page served with a success status
+func isSoftNotFound(path string, resp *httpResponse, baseline *httpResponse) bool {
+	if resp.StatusCode != baseline.StatusCode {
+		return false
+	}
+	if resp.Hash == baseline.Hash {
+		return true
+	}
+
+	// Soft-404 pages often echo the requested path, so compare the sizes
+	// without it and allow a small difference
+	body := bytes.ReplaceAll(resp.Body, []byte(strings.TrimPrefix(path, "/")), nil)
+	diff := len(body) - len(baseline.Body)
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= len(baseline.Body)/20
+}
+
+var (
+	envLineRegex  = regexp.MustCompile(`(?m)^\s*(export\s+)?[A-Za-z_][A-Za-z0-9_]*\s*=`)
+	gitHashRegex  = regexp.MustCompile(`^[0-9a-f]{40}\s*$`)
+	robotsRegex   = regexp.MustCompile(`(?im)^\s*(user-agent|disallow|allow|sitemap)\s*:`)
+	sqlDumpRegex  = regexp.MustCompile(`(?i)(create table|insert into|-- mysql dump|postgresql database dump)`)
+	htaccessRegex = regexp.MustCompile(`(?im)^\s*(rewriteengine|rewriterule|options|deny from|allow from|require|authtype|errordocument|<ifmodule)`)
+)
+
+// fileValidators check that a file body matches what its path promises,
+// keyed by the path suffix of well known files
+var fileValidators = map[string]func(body []byte) bool{
+	"/.git/HEAD": func(body []byte) bool {
+		return bytes.HasPrefix(body, []byte("ref:")) || gitHashRegex.Match(body)
+	},
+	"/.git/config": func(body []byte) bool {
+		return bytes.Contains(body, []byte("[core]"))
+	},
+	"/.env": func(body []byte) bool {
+		return !isHTML(body) && envLineRegex.Match(body)
+	},
+	"/.htaccess": func(body []byte) bool {
+		return !isHTML(body) && htaccessRegex.Match(body)
+	},
+	"/.DS_Store": func(body []byte) bool {
+		return bytes.HasPrefix(body, []byte("\x00\x00\x00\x01Bud1"))
+	},
+	"/robots.txt": func(body []byte) bool {
+		return !isHTML(body) && robotsRegex.Match(body)
+	},
+}
+
+// extensionValidators check the body of files by their extension
+var extensionValidators = map[string]func(body []byte) bool{
+	".xml": func(body []byte) bool {
+		return bytes.Contains(body, []byte("<?xml")) || bytes.Contains(body, []byte("<urlset")) || bytes.Contains(body, []byte("<sitemapindex"))
+	},
+	".php": func(body []byte) bool {
+		// PHP files are only interesting when the source is disclosed
+		return bytes.Contains(body, []byte("<?php"))
+	},
+	".json": func(body []byte) bool {
+		return json.Valid(body)
+	},
+	".sql": func(body []byte) bool {
+		return !isHTML(body) && sqlDumpRegex.Match(body)
+	},
+	".zip": func(body []byte) bool {
+		return bytes.HasPrefix(body, []byte("PK\x03\x04"))
+	},
+	".gz": func(body []byte) bool {
+		return bytes.HasPrefix(body, []byte("\x1f\x8b"))
+	},
+	".tgz": func(body []byte) bool {
+		return bytes.HasPrefix(body, []byte("\x1f\x8b"))
+	},
+}
+
+// findValidator returns the content validator for a path, or nil if the file
+// type has none
+func findValidator(path string) func(body []byte) bool {
+	for suffix, validator := range fileValidators {
+		if strings.HasSuffix(path, suffix) {
+			return validator
+		}
+	}
+	return extensionValidators[pathpkg.Ext(path)]
+}
+
+// isHTML reports whether a body looks like an HTML document
+func isHTML(body []byte) bool {
+	start := bytes.ToLower(bytes.TrimSpace(body[:min(len(body), 512)]))
+	return bytes.HasPrefix(start, []byte("<!doctype html")) || bytes.Contains(start, []byte("<html"))
+}
+
+// saveFile writes a downloaded file below the subdomain directory and returns its path
+func saveFile(outputDir string, path string, body []byte) (string, error) {
+	// Create file path, directories are saved as an index file
+	filePath := filepath.Join(outputDir, strings.TrimPrefix(path, "/"))
+	if strings.HasSuffix(path, "/") {
+		filePath = filepath.Join(filePath, "index.html")
+	}
+
+	// Create directory if needed
+	err := os.MkdirAll(filepath.Dir(filePath), 0755)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.WriteFile(filePath, body, 0644); err != nil {
+		return "", err
+	}
+
+	return filePath, nil
+}
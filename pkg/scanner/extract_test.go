@@ -0,0 +1,119 @@
+package scanner
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIsFileFoundSoftNotFound(t *testing.T) {
+	// A catch-all host answering 200 with a page echoing the path, plus real
+	// files sized just inside and just outside the 5% tolerance
+	var inside, outside int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/notes-inside.txt":
+			w.Write([]byte(strings.Repeat("a", inside)))
+		case "/notes-outside.txt":
+			w.Write([]byte(strings.Repeat("b", outside)))
+		case "/api/status.json":
+			w.Write([]byte(`{"status":"ok","version":"1.2.3"}`))
+		default:
+			fmt.Fprintf(w, "<html><body><h1>Welcome</h1><p>The page %s could not be found.</p>%s</body></html>", r.URL.Path, strings.Repeat(" ", 900))
+		}
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	baseline := fetchBaseline("http", host)
+	if baseline == nil {
+		t.Fatal("fetchBaseline() = nil")
+	}
+	size := len(baseline.Body)
+	inside = size + size/20
+	outside = size + size/20 + 1
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/admin/backup", false},
+		{"/a-much-longer-path/that/is/echoed/back.bak", false},
+		{"/notes-inside.txt", false},
+		{"/notes-outside.txt", true},
+		{"/config.json", false},
+		{"/api/status.json", true},
+	}
+
+	for _, test := range tests {
+		resp, err := fetchURL(server.URL + test.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := isFileFound(test.path, resp, baseline); got != test.want {
+			t.Errorf("isFileFound(%s) = %v, want %v", test.path, got, test.want)
+		}
+	}
+}
+
+func TestIsFileFoundCatchAllValidContent(t *testing.T) {
+	// An API answering every path with the same valid JSON is not a hit
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"error":"not found"}`))
+	}))
+	defer server.Close()
+
+	baseline := fetchBaseline("http", strings.TrimPrefix(server.URL, "http://"))
+	resp, err := fetchURL(server.URL + "/package.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isFileFound("/package.json", resp, baseline) {
+		t.Error("catch-all JSON reported as found")
+	}
+	if !isFileFound("/package.json", resp, nil) {
+		t.Error("valid JSON without a baseline not reported as found")
+	}
+}
+
+func TestFileValidators(t *testing.T) {
+	page := "<!DOCTYPE html><html><body>Not here</body></html>"
+	tests := []struct {
+		path string
+		body string
+		want bool
+	}{
+		{"/.git/HEAD", "ref: refs/heads/main\n", true},
+		{"/.git/HEAD", "0123456789abcdef0123456789abcdef01234567\n", true},
+		{"/.git/HEAD", page, false},
+		{"/.git/config", "[core]\n\trepositoryformatversion = 0\n", true},
+		{"/.env", "DB_HOST=localhost\nexport SECRET=x\n", true},
+		{"/.env", page, false},
+		{"/.htaccess", "RewriteEngine On\n", true},
+		{"/robots.txt", "User-agent: *\nDisallow: /admin\n", true},
+		{"/robots.txt", page, false},
+		{"/sitemap.xml", `<?xml version="1.0"?><urlset></urlset>`, true},
+		{"/index.php", "<?php echo 1;", true},
+		{"/index.php", page, false},
+		{"/dump.sql", "-- MySQL dump 10.13\nCREATE TABLE users", true},
+		{"/backup.zip", "PK\x03\x04rest", true},
+		{"/backup.zip", page, false},
+		{"/backup.tgz", "\x1f\x8b\x08", true},
+	}
+
+	for _, test := range tests {
+		validator := findValidator(test.path)
+		if validator == nil {
+			t.Fatalf("findValidator(%s) = nil", test.path)
+		}
+		if got := validator([]byte(test.body)); got != test.want {
+			t.Errorf("validator(%s, %.20q) = %v, want %v", test.path, test.body, got, test.want)
+		}
+	}
+
+	if findValidator("/backup.bak") != nil {
+		t.Error("findValidator(/backup.bak) should have no validator")
+	}
+}
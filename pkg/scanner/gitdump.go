@@ -0,0 +1,441 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/SayerLinux/sub/pkg/utils"
+)
+
+const (
+	// DefaultGitMaxObjects is the default maximum number of loose objects downloaded per repository
+	DefaultGitMaxObjects = 5000
+
+	// DefaultGitMaxSize is the default maximum number of bytes downloaded per repository
+	DefaultGitMaxSize = 100 << 20
+)
+
+// gitStaticFiles are the files fetched from every exposed repository
+var gitStaticFiles = []string{
+	"HEAD",
+	"ORIG_HEAD",
+	"FETCH_HEAD",
+	"COMMIT_EDITMSG",
+	"config",
+	"description",
+	"packed-refs",
+	"index",
+	"info/refs",
+	"info/exclude",
+	"logs/HEAD",
+	"objects/info/packs",
+	"refs/heads/master",
+	"refs/heads/main",
+	"refs/heads/develop",
+	"refs/remotes/origin/HEAD",
+	"refs/remotes/origin/master",
+	"refs/remotes/origin/main",
+	"refs/stash",
+}
+
+var (
+	gitObjectRegex = regexp.MustCompile(`\b[0-9a-f]{40}\b`)
+	gitRefRegex    = regexp.MustCompile(`(?m)^ref:\s*(refs/\S+)`)
+	gitPackRegex   = regexp.MustCompile(`pack-[0-9a-f]{40}\.pack`)
+)
+
+// GitDumpConfig bounds the reconstruction of an exposed .git directory
+type GitDumpConfig struct {
+	MaxObjects int
+	MaxSize    int64
+	Logger     *utils.Logger
+}
+
+// GitDumpResult summarises a reconstructed repository
+type GitDumpResult struct {
+	Directory    string
+	Files        []string
+	Objects      int
+	Packs        int
+	Size         int64
+	TrackedFiles []string
+	Truncated    bool
+}
+
+// gitDumper holds the state of a single repository reconstruction
+type gitDumper struct {
+	baseURL string
+	gitDir  string
+	config  GitDumpConfig
+	result  *GitDumpResult
+	seen    map[string]bool
+	queue   []string
+}
+
+// DumpGitRepository reconstructs the .git directory exposed at baseURL (for
+// example "https://host/.git/") into outputDir/.git. It fetches the refs,
+// config, index, loose objects reachable from the refs and index, and any
+// packs, staying within the configured object and size limits.
+func DumpGitRepository(baseURL string, outputDir string, config GitDumpConfig) (*GitDumpResult, error) {
+	if config.MaxObjects <= 0 {
+		config.MaxObjects = DefaultGitMaxObjects
+	}
+	if config.MaxSize <= 0 {
+		config.MaxSize = DefaultGitMaxSize
+	}
+	if config.Logger == nil {
+		config.Logger = utils.NewLogger(false, nil)
+	}
+
+	// Git only recognises a directory with objects and refs as a repository
+	gitDir := filepath.Join(outputDir, ".git")
+	for _, dir := range []string{"objects", "refs/heads", "refs/tags"} {
+		if err := utils.EnsureDirectory(filepath.Join(gitDir, filepath.FromSlash(dir))); err != nil {
+			return nil, fmt.Errorf("failed to create git directory: %v", err)
+		}
+	}
+
+	d := &gitDumper{
+		baseURL: strings.TrimSuffix(baseURL, "/") + "/",
+		gitDir:  gitDir,
+		config:  config,
+		result:  &GitDumpResult{Directory: outputDir},
+		seen:    make(map[string]bool),
+	}
+
+	d.config.Logger.Info("Reconstructing git repository from %s", d.baseURL)
+
+	// Static files first, they reference everything else
+	for _, name := range gitStaticFiles {
+		data, ok := d.fetch(name)
+		if !ok {
+			continue
+		}
+
+		switch name {
+		case "index":
+			entries, err := parseGitIndex(data)
+			if err != nil {
+				d.config.Logger.Debug("Failed to parse git index from %s: %v", d.baseURL, err)
+				break
+			}
+			for _, entry := range entries {
+				d.result.TrackedFiles = append(d.result.TrackedFiles, entry.Path)
+				d.enqueue(entry.Hash)
+			}
+		case "objects/info/packs":
+			for _, pack := range gitPackRegex.FindAllString(string(data), -1) {
+				d.fetchPack(strings.TrimSuffix(pack, ".pack"))
+			}
+		default:
+			d.scanReferences(data)
+		}
+	}
+
+	// Walk the object graph from everything referenced so far
+	for len(d.queue) > 0 && d.result.Objects < d.config.MaxObjects && d.result.Size < d.config.MaxSize {
+		hash := d.queue[0]
+		d.queue = d.queue[1:]
+		d.fetchObject(hash)
+	}
+	if len(d.queue) > 0 {
+		d.result.Truncated = true
+	}
+
+	if d.result.Truncated {
+		d.config.Logger.Warning("Stopped reconstructing %s after %d objects (%d bytes)", d.baseURL, d.result.Objects, d.result.Size)
+	}
+
+	if len(d.result.TrackedFiles) > 0 {
+		listing := strings.Join(d.result.TrackedFiles, "\n") + "\n"
+		if err := utils.SaveToFile(filepath.Join(outputDir, "tracked_files.txt"), listing); err != nil {
+			return d.result, err
+		}
+	}
+
+	d.config.Logger.Success("Reconstructed %s: %d files, %d objects, %d packs, %d tracked paths (%d bytes)",
+		d.baseURL, len(d.result.Files), d.result.Objects, d.result.Packs, len(d.result.TrackedFiles), d.result.Size)
+	return d.result, nil
+}
+
+// fetch downloads a file relative to the .git directory and stores it locally
+func (d *gitDumper) fetch(name string) ([]byte, bool) {
+	// Ref names come from the server, never let them escape the .git directory
+	if strings.Contains(name, "..") {
+		return nil, false
+	}
+
+	remaining := d.config.MaxSize - d.result.Size
+	if remaining <= 0 {
+		d.result.Truncated = true
+		return nil, false
+	}
+
+	resp, err := fetchLimited(d.baseURL+name, remaining+1)
+	if err != nil || resp.StatusCode != 200 || len(resp.Body) == 0 || isHTML(resp.Body) {
+		return nil, false
+	}
+	if int64(len(resp.Body)) > remaining {
+		d.result.Truncated = true
+		return nil, false
+	}
+
+	if err := utils.SaveToFile(filepath.Join(d.gitDir, filepath.FromSlash(name)), string(resp.Body)); err != nil {
+		d.config.Logger.Debug("Failed to save %s: %v", name, err)
+		return nil, false
+	}
+
+	d.result.Files = append(d.result.Files, name)
+	d.result.Size += int64(len(resp.Body))
+	return resp.Body, true
+}
+
+// scanReferences queues object hashes and fetches refs mentioned in a file
+func (d *gitDumper) scanReferences(data []byte) {
+	for _, match := range gitRefRegex.FindAllStringSubmatch(string(data), -1) {
+		ref := match[1]
+		if !d.seen[ref] {
+			d.seen[ref] = true
+			if refData, ok := d.fetch(ref); ok {
+				d.scanReferences(refData)
+			}
+		}
+	}
+
+	for _, hash := range gitObjectRegex.FindAllString(string(data), -1) {
+		d.enqueue(hash)
+	}
+}
+
+// enqueue adds an object to the download queue unless it was seen before
+func (d *gitDumper) enqueue(hash string) {
+	if hash == strings.Repeat("0", 40) || d.seen[hash] {
+		return
+	}
+	d.seen[hash] = true
+	d.queue = append(d.queue, hash)
+}
+
+// fetchPack downloads a pack file together with its index
+func (d *gitDumper) fetchPack(name string) {
+	if _, ok := d.fetch("objects/pack/" + name + ".idx"); !ok {
+		return
+	}
+	if _, ok := d.fetch("objects/pack/" + name + ".pack"); ok {
+		d.result.Packs++
+	}
+}
+
+// fetchObject downloads a loose object and queues the objects it references.
+// Objects that are missing are assumed to live in a pack.
+func (d *gitDumper) fetchObject(hash string) {
+	name := fmt.Sprintf("objects/%s/%s", hash[:2], hash[2:])
+	data, ok := d.fetch(name)
+	if !ok {
+		return
+	}
+	d.result.Objects++
+
+	kind, content, err := parseGitObject(data, d.config.MaxSize)
+	if err != nil {
+		d.config.Logger.Debug("Invalid git object %s from %s: %v", hash, d.baseURL, err)
+		return
+	}
+
+	switch kind {
+	case "commit", "tag":
+		// Tree, parent and tagged object hashes appear in the header lines
+		header := content
+		if end := bytes.Index(content, []byte("\n\n")); end != -1 {
+			header = content[:end]
+		}
+		for _, ref := range gitObjectRegex.FindAll(header, -1) {
+			d.enqueue(string(ref))
+		}
+	case "tree":
+		for _, ref := range parseGitTree(content) {
+			d.enqueue(ref)
+		}
+	}
+}
+
+// parseGitObject inflates a loose object and returns its type and content.
+// Objects inflating to more than limit bytes are rejected, as the download
+// limit only bounds their compressed size.
+func parseGitObject(data []byte, limit int64) (string, []byte, error) {
+	reader, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return "", nil, err
+	}
+	defer reader.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return "", nil, err
+	}
+	if int64(len(raw)) > limit {
+		return "", nil, fmt.Errorf("object inflates to more than %d bytes", limit)
+	}
+
+	nul := bytes.IndexByte(raw, 0)
+	if nul == -1 {
+		return "", nil, fmt.Errorf("missing object header")
+	}
+
+	header := strings.Fields(string(raw[:nul]))
+	if len(header) != 2 {
+		return "", nil, fmt.Errorf("malformed object header %q", raw[:nul])
+	}
+
+	return header[0], raw[nul+1:], nil
+}
+
+// parseGitTree returns the hashes of the entries of a tree object
+func parseGitTree(content []byte) []string {
+	var hashes []string
+
+	for len(content) > 0 {
+		// Each entry is "<mode> <name>\0<20 byte hash>"
+		nul := bytes.IndexByte(content, 0)
+		if nul == -1 || len(content) < nul+21 {
+			break
+		}
+
+		mode := content[:bytes.IndexByte(content[:nul], ' ')+1]
+		hash := hex.EncodeToString(content[nul+1 : nul+21])
+		content = content[nul+21:]
+
+		// Submodule commits live in another repository
+		if string(mode) == "160000 " {
+			continue
+		}
+		hashes = append(hashes, hash)
+	}
+
+	return hashes
+}
+
+// gitIndexEntry is a file tracked by a git index
+type gitIndexEntry struct {
+	Path string
+	Hash string
+}
+
+// parseGitIndex parses a version 2, 3 or 4 git index file. Entries whose
+// path could escape a checkout are left out.
+func parseGitIndex(data []byte) ([]gitIndexEntry, error) {
+	if len(data) < 12+sha1.Size || !bytes.HasPrefix(data, []byte("DIRC")) {
+		return nil, fmt.Errorf("not a git index")
+	}
+
+	version := binary.BigEndian.Uint32(data[4:8])
+	if version < 2 || version > 4 {
+		return nil, fmt.Errorf("unsupported index version %d", version)
+	}
+	count := binary.BigEndian.Uint32(data[8:12])
+
+	// The entry count comes from the server, so never trust it for more
+	// entries than the data can hold
+	reader := bufio.NewReader(bytes.NewReader(data[12:]))
+	entries := make([]gitIndexEntry, 0, min(int(count), len(data)/62))
+	previous := ""
+
+	for i := uint32(0); i < count; i++ {
+		// ctime, mtime, dev, ino, mode, uid, gid and size, then the hash and flags
+		fixed := make([]byte, 62)
+		if _, err := io.ReadFull(reader, fixed); err != nil {
+			return entries, fmt.Errorf("truncated index entry %d: %v", i, err)
+		}
+		hash := hex.EncodeToString(fixed[40:60])
+		flags := binary.BigEndian.Uint16(fixed[60:62])
+		entryLength := 62
+
+		// Extended flags follow when the extended bit is set
+		if version >= 3 && flags&0x4000 != 0 {
+			if _, err := reader.Discard(2); err != nil {
+				return entries, err
+			}
+			entryLength += 2
+		}
+
+		var path string
+		if version == 4 {
+			// Paths are prefix compressed against the previous entry
+			strip, err := readGitVarint(reader)
+			if err != nil {
+				return entries, err
+			}
+			suffix, err := reader.ReadString(0)
+			if err != nil {
+				return entries, err
+			}
+			if int(strip) > len(previous) {
+				return entries, fmt.Errorf("invalid path compression in entry %d", i)
+			}
+			path = previous[:len(previous)-int(strip)] + strings.TrimSuffix(suffix, "\x00")
+		} else {
+			name, err := reader.ReadString(0)
+			if err != nil {
+				return entries, err
+			}
+			path = strings.TrimSuffix(name, "\x00")
+			entryLength += len(name)
+
+			// Entries are padded with NULs to a multiple of eight bytes
+			if padding := (8 - entryLength%8) % 8; padding > 0 {
+				if _, err := reader.Discard(padding); err != nil {
+					return entries, err
+				}
+			}
+		}
+
+		if isSafeGitPath(path) {
+			entries = append(entries, gitIndexEntry{Path: path, Hash: hash})
+		}
+		previous = path
+	}
+
+	return entries, nil
+}
+
+// isSafeGitPath reports whether a tracked path stays inside a checkout, the
+// way git itself validates index paths
+func isSafeGitPath(path string) bool {
+	if path == "" || strings.HasPrefix(path, "/") || strings.Contains(path, "\\") {
+		return false
+	}
+	for _, part := range strings.Split(path, "/") {
+		if part == "" || part == "." || part == ".." || strings.EqualFold(part, ".git") {
+			return false
+		}
+	}
+	return true
+}
+
+// readGitVarint reads the offset encoded integer used by index version 4
+func readGitVarint(reader *bufio.Reader) (uint64, error) {
+	b, err := reader.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+
+	value := uint64(b & 0x7f)
+	for b&0x80 != 0 {
+		b, err = reader.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		value = ((value + 1) << 7) | uint64(b&0x7f)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,167 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Index, tree and loose object written by git 2.39 for a repository holding
+// README.md, src/app/main.go and src/app/util.go
+const (
+	testGitIndexV2 = "RElSQwAAAAIAAAADatXNxRkJQ4Fq1c3FGQlDgQAA/gAAksITAACBpAAAAAAAAAAAAAAAAniYGSJhOyr7YCUEL/a9h4rBmU6FAAlSRUFETUUubWQAatXNxRkJQ4Fq1c3FGQlDgQAA/gAAksIjAACBpAAAAAAAAAAAAAAAAmF4B5gijRevLTT85M+981VWgyRyAA9zcmMvYXBwL21haW4uZ28AAABq1c3FGQlDgWrVzcUZCUOBAAD+AACSwjMAAIGkAAAAAAAAAAAAAAAC8q1sdvARWmulsARWqEmBDn7AryAAD3NyYy9hcHAvdXRpbC5nbwAAAK+5Ydep7DVgf4W4ab29wCTvk/v5"
+	testGitIndexV4 = "RElSQwAAAAQAAAADatXNxRkJQ4Fq1c3FGQlDgQAA/gAAksITAACBpAAAAAAAAAAAAAAAAniYGSJhOyr7YCUEL/a9h4rBmU6FAAkAUkVBRE1FLm1kAGrVzcUZCUOBatXNxRkJQ4EAAP4AAJLCIwAAgaQAAAAAAAAAAAAAAAJheAeYIo0Xry00/OTPvfNVVoMkcgAPCXNyYy9hcHAvbWFpbi5nbwBq1c3FGQlDgWrVzcUZCUOBAAD+AACSwjMAAIGkAAAAAAAAAAAAAAAC8q1sdvARWmulsARWqEmBDn7AryAADwd1dGlsLmdvAKAdXys3zLdEyKuDA3YSXE34sUlm"
+	testGitTree    = "MTAwNjQ0IFJFQURNRS5tZAB4mBkiYTsq+2AlBC/2vYeKwZlOhTQwMDAwIHNyYwBiUzpP9DvNnJNFLYA1bGi+HATvQg=="
+	testGitObject  = "eAErKUpNVTAzZzA0MDAzMVEIcnV08XXVy01hqJghqZRorfU7QZVF/9ve9q6DM/1aTQyAQKG4KJkhKdjK/4v12TmTXXUbTHMy9smwvHcCAHkNGb4="
+)
+
+func decodeTestData(t *testing.T, data string) []byte {
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+// buildGitIndex writes a version 2 index holding the given paths
+func buildGitIndex(count uint32, paths ...string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("DIRC")
+	binary.Write(&buf, binary.BigEndian, uint32(2))
+	binary.Write(&buf, binary.BigEndian, count)
+
+	for i, path := range paths {
+		fixed := make([]byte, 62)
+		fixed[40] = byte(i + 1)
+		binary.BigEndian.PutUint16(fixed[60:], uint16(len(path)))
+		buf.Write(fixed)
+		buf.WriteString(path)
+		buf.WriteByte(0)
+		buf.Write(make([]byte, (8-(62+len(path)+1)%8)%8))
+	}
+
+	buf.Write(make([]byte, 20))
+	return buf.Bytes()
+}
+
+func TestParseGitIndex(t *testing.T) {
+	want := []gitIndexEntry{
+		{"README.md", "78981922613b2afb6025042ff6bd878ac1994e85"},
+		{"src/app/main.go", "61780798228d17af2d34fce4cfbdf35556832472"},
+		{"src/app/util.go", "f2ad6c76f0115a6ba5b00456a849810e7ec0af20"},
+	}
+
+	for name, data := range map[string]string{"v2": testGitIndexV2, "v4": testGitIndexV4} {
+		entries, err := parseGitIndex(decodeTestData(t, data))
+		if err != nil {
+			t.Fatalf("%s: parseGitIndex() failed: %v", name, err)
+		}
+		if !reflect.DeepEqual(entries, want) {
+			t.Errorf("%s: parseGitIndex() = %v, want %v", name, entries, want)
+		}
+	}
+}
+
+func TestParseGitIndexHostile(t *testing.T) {
+	v2 := decodeTestData(t, testGitIndexV2)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		entries int
+		err     bool
+	}{
+		{"not an index", []byte(strings.Repeat("<html>", 10)), 0, true},
+		{"too short", []byte("DIRC\x00\x00\x00\x02"), 0, true},
+		{"unsupported version", append([]byte("DIRC\x00\x00\x00\x05\x00\x00\x00\x01"), make([]byte, 20)...), 0, true},
+		{"truncated", v2[:130], 1, true},
+		{"oversized count", buildGitIndex(0xffffffff, "README.md"), 1, true},
+		{"unsafe paths", buildGitIndex(6, "../../etc/passwd", "/etc/shadow", "a/../../b", ".git/hooks/pre-commit", "src/.GIT/config", "docs/guide.md"), 1, false},
+	}
+
+	for _, test := range tests {
+		entries, err := parseGitIndex(test.data)
+		if (err != nil) != test.err {
+			t.Errorf("%s: parseGitIndex() error = %v, want error %v", test.name, err, test.err)
+		}
+		if len(entries) != test.entries {
+			t.Errorf("%s: parseGitIndex() = %v, want %d entries", test.name, entries, test.entries)
+		}
+		if cap(entries) > len(test.data)/62 {
+			t.Errorf("%s: capacity %d exceeds what the data can hold", test.name, cap(entries))
+		}
+	}
+}
+
+func TestReadGitVarint(t *testing.T) {
+	tests := []struct {
+		data string
+		want uint64
+		err  bool
+	}{
+		{"\x00", 0, false},
+		{"\x09", 9, false},
+		{"\x7f", 127, false},
+		{"\x80\x00", 128, false},
+		{"\x81\x00", 256, false},
+		{"\xff\x7f", 16511, false},
+		{"\x80", 0, true},
+		{"", 0, true},
+	}
+
+	for _, test := range tests {
+		got, err := readGitVarint(bufio.NewReader(strings.NewReader(test.data)))
+		if (err != nil) != test.err || got != test.want {
+			t.Errorf("readGitVarint(%q) = %d, %v, want %d, error %v", test.data, got, err, test.want, test.err)
+		}
+	}
+}
+
+func TestParseGitTree(t *testing.T) {
+	tree := decodeTestData(t, testGitTree)
+	want := []string{
+		"78981922613b2afb6025042ff6bd878ac1994e85",
+		"62533a4ff43bcd9c93452d80356c68be1c04ef42",
+	}
+	if got := parseGitTree(tree); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseGitTree() = %v, want %v", got, want)
+	}
+
+	// Submodule commits are skipped
+	submodule := append(append([]byte(nil), tree...), "160000 vendor\x00"+strings.Repeat("\x11", 20)...)
+	if got := parseGitTree(submodule); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseGitTree() with a submodule = %v, want %v", got, want)
+	}
+
+	// A truncated entry ends the tree
+	if got := parseGitTree(tree[:len(tree)-5]); !reflect.DeepEqual(got, want[:1]) {
+		t.Errorf("parseGitTree() of a truncated tree = %v, want %v", got, want[:1])
+	}
+	if got := parseGitTree([]byte("no entries here")); got != nil {
+		t.Errorf("parseGitTree() of garbage = %v", got)
+	}
+}
+
+func TestParseGitObject(t *testing.T) {
+	object := decodeTestData(t, testGitObject)
+
+	kind, content, err := parseGitObject(object, DefaultGitMaxSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kind != "tree" || !bytes.Equal(content, decodeTestData(t, testGitTree)) {
+		t.Errorf("parseGitObject() = %s, %q", kind, content)
+	}
+
+	// Objects inflating beyond the limit are rejected
+	if _, _, err := parseGitObject(object, 32); err == nil {
+		t.Error("parseGitObject() accepted an object over the limit")
+	}
+	if _, _, err := parseGitObject([]byte("not zlib"), DefaultGitMaxSize); err == nil {
+		t.Error("parseGitObject() accepted invalid data")
+	}
+}
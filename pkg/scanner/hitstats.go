@@ -0,0 +1,142 @@
+package scanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// HitStats records, per wordlist, how many domains were brute forced with it
+// and how often each entry resolved, so lists can be reordered by what
+// actually finds names
+type HitStats struct {
+	path      string
+	Wordlists map[string]*WordlistHits `json:"wordlists"`
+}
+
+// WordlistHits holds the hit counts of the entries of one wordlist
+type WordlistHits struct {
+	Scans int            `json:"scans"`
+	Hits  map[string]int `json:"hits"`
+}
+
+// DefaultHitStatsPath returns the default location of the wordlist hit
+// statistics, inside the user configuration directory
+func DefaultHitStatsPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "sub", "wordlist-stats.json")
+}
+
+// LoadHitStats loads a statistics file. A missing file yields empty
+// statistics, and an empty path statistics that are never saved.
+func LoadHitStats(path string) (*HitStats, error) {
+	stats := &HitStats{path: path, Wordlists: map[string]*WordlistHits{}}
+	if path == "" {
+		return stats, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return stats, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hit statistics: %v", err)
+	}
+
+	if err := json.Unmarshal(data, stats); err != nil {
+		return nil, fmt.Errorf("invalid hit statistics %s: %v", path, err)
+	}
+	if stats.Wordlists == nil {
+		stats.Wordlists = map[string]*WordlistHits{}
+	}
+	return stats, nil
+}
+
+// hitStatsKey is the name a wordlist is recorded under: the name of a
+// built-in wordlist or the absolute path of a file, so different lists with
+// the same file name keep their own statistics
+func hitStatsKey(wordlist string) string {
+	if isBuiltinWordlist(wordlist) {
+		return wordlist
+	}
+	if path, err := filepath.Abs(wordlist); err == nil {
+		return path
+	}
+	return wordlist
+}
+
+// Lookup returns the statistics recorded for a wordlist, or nil
+func (h *HitStats) Lookup(wordlist string) *WordlistHits {
+	return h.Wordlists[hitStatsKey(wordlist)]
+}
+
+// Record adds a run of a wordlist over the given number of domains and the
+// entries that resolved, once per domain they were found on
+func (h *HitStats) Record(wordlist string, scans int, hits map[string]int) {
+	key := hitStatsKey(wordlist)
+	entry := h.Wordlists[key]
+	if entry == nil {
+		entry = &WordlistHits{Hits: map[string]int{}}
+		h.Wordlists[key] = entry
+	}
+	if entry.Hits == nil {
+		entry.Hits = map[string]int{}
+	}
+
+	entry.Scans += scans
+	for word, count := range hits {
+		entry.Hits[strings.ToLower(word)] += count
+	}
+}
+
+// Save writes the statistics file, replacing it only once it is fully written
+func (h *HitStats) Save() error {
+	if h.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+		return fmt.Errorf("failed to create statistics directory: %v", err)
+	}
+	tmp := h.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to save hit statistics: %v", err)
+	}
+	return os.Rename(tmp, h.path)
+}
+
+// Count returns the number of recorded hits of an entry
+func (w *WordlistHits) Count(word string) int {
+	if w == nil {
+		return 0
+	}
+	return w.Hits[strings.ToLower(word)]
+}
+
+// Rate returns the share of scans an entry resolved in
+func (w *WordlistHits) Rate(word string) float64 {
+	if w == nil || w.Scans == 0 {
+		return 0
+	}
+	return float64(w.Count(word)) / float64(w.Scans)
+}
+
+// Rank returns the words ordered by hit count, most found first. Words with
+// the same count keep their order in the list.
+func (w *WordlistHits) Rank(words []string) []string {
+	ranked := append([]string(nil), words...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return w.Count(ranked[i]) > w.Count(ranked[j])
+	})
+	return ranked
+}
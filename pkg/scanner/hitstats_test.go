@@ -0,0 +1,189 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestHitStatsRecordAndSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "wordlist-stats.json")
+
+	stats, err := LoadHitStats(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Lookup("lists/default.txt") != nil {
+		t.Fatal("missing file should yield empty statistics")
+	}
+
+	stats.Record("lists/default.txt", 2, map[string]int{"www": 2, "API": 1})
+	if err := stats.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err = LoadHitStats(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats.Record("lists/../lists/default.txt", 1, map[string]int{"api": 1, "mail": 1})
+	if err := stats.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err = LoadHitStats(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	abs, err := filepath.Abs("lists/default.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hits := stats.Lookup(abs)
+	if hits == nil {
+		t.Fatal("statistics not found by absolute path")
+	}
+	if hits.Scans != 3 {
+		t.Errorf("Scans = %d, want 3", hits.Scans)
+	}
+	want := map[string]int{"www": 2, "api": 2, "mail": 1}
+	if !reflect.DeepEqual(hits.Hits, want) {
+		t.Errorf("Hits = %v, want %v", hits.Hits, want)
+	}
+	if rate := hits.Rate("WWW"); rate < 0.66 || rate > 0.67 {
+		t.Errorf("Rate(WWW) = %v", rate)
+	}
+}
+
+func TestWordlistHitsRank(t *testing.T) {
+	hits := &WordlistHits{Scans: 4, Hits: map[string]int{"mail": 1, "www": 4, "vpn": 1}}
+
+	got := hits.Rank([]string{"dev", "vpn", "mail", "www", "test"})
+	want := []string{"www", "vpn", "mail", "dev", "test"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Rank() = %v, want %v", got, want)
+	}
+
+	var none *WordlistHits
+	if got := none.Rank([]string{"b", "a"}); !reflect.DeepEqual(got, []string{"b", "a"}) {
+		t.Errorf("Rank() without statistics = %v", got)
+	}
+}
+
+func TestHitStatsKeys(t *testing.T) {
+	stats, err := LoadHitStats("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Lists that only share a file name are kept apart
+	stats.Record("/a/words.txt", 1, map[string]int{"www": 1})
+	stats.Record("/b/words.txt", 1, map[string]int{"mail": 1})
+	stats.Record(DefaultWordlist, 1, map[string]int{"api": 1})
+
+	if hits := stats.Lookup("/a/words.txt"); hits == nil || !reflect.DeepEqual(hits.Hits, map[string]int{"www": 1}) {
+		t.Errorf("/a/words.txt = %+v", hits)
+	}
+	if hits := stats.Lookup("/b/words.txt"); hits == nil || !reflect.DeepEqual(hits.Hits, map[string]int{"mail": 1}) {
+		t.Errorf("/b/words.txt = %+v", hits)
+	}
+	if stats.Lookup("words.txt") != nil {
+		t.Error("statistics found by file name alone")
+	}
+
+	// Built-in lists keep their bare name
+	if _, ok := stats.Wordlists[DefaultWordlist]; !ok {
+		t.Errorf("built-in list recorded under %v", stats.Wordlists)
+	}
+}
+
+func TestScannerSaveHitStats(t *testing.T) {
+	paths := writeWordlists(t, "www\napi\nmail\n")
+	statsPath := filepath.Join(t.TempDir(), "stats.json")
+
+	s := NewScanner(Config{
+		Targets:  []string{"example.com", "dev.example.org"},
+		Wordlist: paths["plain"],
+		HitStats: statsPath,
+	})
+	if err := s.openWordlist(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.words.Close()
+	readWordlist(s)
+
+	s.recordHit("WWW.example.com")
+	s.recordHit("www.dev.example.org")
+	s.recordHit("api.dev.example.org")
+	s.saveHitStats()
+
+	stats, err := LoadHitStats(statsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hits := stats.Lookup(paths["plain"])
+	if hits == nil || hits.Scans != 2 {
+		t.Fatalf("Lookup() = %+v, want 2 scans", hits)
+	}
+	want := map[string]int{"www": 2, "api": 1}
+	if !reflect.DeepEqual(hits.Hits, want) {
+		t.Errorf("Hits = %v, want %v", hits.Hits, want)
+	}
+}
+
+// readWordlist queues the brute force jobs of a scanner and drops them,
+// reading its wordlist to the end
+func readWordlist(s *Scanner) {
+	jobs := make(chan scanJob)
+	go func() {
+		defer close(jobs)
+		s.sendBruteForceJobs(jobs)
+	}()
+	for range jobs {
+	}
+}
+
+func TestScannerSaveHitStatsPartial(t *testing.T) {
+	paths := writeWordlists(t, "www\napi\nmail\n")
+	dir := t.TempDir()
+	targets := []string{"example.com"}
+
+	// A resumed pass only covers the end of the list
+	resumeFile := filepath.Join(dir, "resume.json")
+	state := &ResumeState{Wordlist: paths["plain"], Targets: targets, Offset: 4}
+	if err := state.Save(resumeFile); err != nil {
+		t.Fatal(err)
+	}
+	resumed := NewScanner(Config{
+		Targets:    targets,
+		Wordlist:   paths["plain"],
+		HitStats:   filepath.Join(dir, "resumed.json"),
+		ResumeFile: resumeFile,
+	})
+	if err := resumed.openWordlist(); err != nil {
+		t.Fatal(err)
+	}
+	defer resumed.words.Close()
+	readWordlist(resumed)
+
+	// A pass that stopped before the end of the list
+	partial := NewScanner(Config{
+		Targets:  targets,
+		Wordlist: paths["plain"],
+		HitStats: filepath.Join(dir, "partial.json"),
+	})
+	if err := partial.openWordlist(); err != nil {
+		t.Fatal(err)
+	}
+	defer partial.words.Close()
+	partial.words.Scan()
+
+	for name, s := range map[string]*Scanner{"resumed": resumed, "partial": partial} {
+		s.recordHit("mail.example.com")
+		s.saveHitStats()
+		if _, err := os.Stat(s.config.HitStats); !os.IsNotExist(err) {
+			t.Errorf("%s pass recorded hit statistics", name)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package scanner
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/SayerLinux/sub/pkg/utils"
+)
+
+// HTTPConfig holds the settings of the shared HTTP engine used for every
+// request sent to a target
+type HTTPConfig struct {
+	// Timeout limits a whole request, ConnectTimeout the TCP connection and
+	// TLS handshake
+	Timeout        time.Duration
+	ConnectTimeout time.Duration
+
+	// MaxConnsPerHost limits the connections open to a single host, idle
+	// ones included
+	MaxConnsPerHost int
+
+	// UserAgents are sent in turn, one per request. Headers are added to
+	// every request and Cookies, if set, keeps the cookies of the session.
+	UserAgents []string
+	Headers    http.Header
+	Cookies    http.CookieJar
+	VerifyTLS  bool
+}
+
+// DefaultHTTPConfig returns the HTTP engine settings used when none are given
+func DefaultHTTPConfig() HTTPConfig {
+	return HTTPConfig{
+		Timeout:         5 * time.Second,
+		ConnectTimeout:  5 * time.Second,
+		MaxConnsPerHost: 10,
+		UserAgents:      []string{utils.DefaultUserAgent},
+	}
+}
+
+// httpEngine is a pooled transport shared by all requests, with the client
+// built on it
+type httpEngine struct {
+	config HTTPConfig
+	client *http.Client
+}
+
+var (
+	engine     *httpEngine
+	engineOnce sync.Once
+	engineLock sync.RWMutex
+)
+
+// The HTTP helpers of the utils package go through the shared engine too
+func init() {
+	utils.SetHTTPClient(httpClient)
+}
+
+// ConfigureHTTP replaces the settings of the shared HTTP engine. It must be
+// called before any request is sent.
+func ConfigureHTTP(config HTTPConfig) {
+	defaults := DefaultHTTPConfig()
+	if config.Timeout <= 0 {
+		config.Timeout = defaults.Timeout
+	}
+	if config.ConnectTimeout <= 0 {
+		config.ConnectTimeout = defaults.ConnectTimeout
+	}
+	if config.MaxConnsPerHost <= 0 {
+		config.MaxConnsPerHost = defaults.MaxConnsPerHost
+	}
+	if len(config.UserAgents) == 0 {
+		config.UserAgents = defaults.UserAgents
+	}
+
+	// Make sure the default engine is not created over this one later
+	engineOnce.Do(func() {})
+
+	engineLock.Lock()
+	engine = newHTTPEngine(config)
+	engineLock.Unlock()
+}
+
+// getHTTPEngine returns the shared HTTP engine, creating it with the default
+// settings on first use
+func getHTTPEngine() *httpEngine {
+	engineOnce.Do(func() {
+		engineLock.Lock()
+		engine = newHTTPEngine(DefaultHTTPConfig())
+		engineLock.Unlock()
+	})
+
+	engineLock.RLock()
+	defer engineLock.RUnlock()
+	return engine
+}
+
+// newHTTPEngine creates the pooled transport and client for a configuration
+func newHTTPEngine(config HTTPConfig) *httpEngine {
+	dialer := &net.Dialer{Timeout: config.ConnectTimeout, KeepAlive: 30 * time.Second}
+	transport := newHTTPTransport(config, dialer.DialContext)
+
+	return &httpEngine{
+		config: config,
+		client: newHTTPClient(config, transport),
+	}
+}
+
+// newHTTPTransport creates a keep-alive transport with HTTP/2, per-host
+// connection limits and the loaded proxies that dials with dial
+func newHTTPTransport(config HTTPConfig, dial func(ctx context.Context, network, address string) (net.Conn, error)) *http.Transport {
+	return &http.Transport{
+		Proxy:                 utils.Proxy,
+		DialContext:           dial,
+		TLSClientConfig:       &tls.Config{InsecureSkipVerify: !config.VerifyTLS},
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          1024,
+		MaxIdleConnsPerHost:   config.MaxConnsPerHost,
+		MaxConnsPerHost:       config.MaxConnsPerHost,
+		IdleConnTimeout:       30 * time.Second,
+		TLSHandshakeTimeout:   config.ConnectTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// newHTTPClient creates a client on a transport that adds the configured
+// headers to every request and returns redirects instead of following them,
+// so callers can inspect or record each hop
+func newHTTPClient(config HTTPConfig, transport *http.Transport) *http.Client {
+	return &http.Client{
+		Timeout:   config.Timeout,
+		Transport: &headerTransport{config: config, base: transport},
+		Jar:       config.Cookies,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// headerTransport sets the user agent and extra headers of the configuration
+// on requests that do not set them already
+type headerTransport struct {
+	config HTTPConfig
+	base   http.RoundTripper
+	next   uint32
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if len(t.config.UserAgents) == 0 && len(t.config.Headers) == 0 {
+		return t.base.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	if len(t.config.UserAgents) > 0 && req.Header.Get("User-Agent") == "" {
+		i := atomic.AddUint32(&t.next, 1) - 1
+		req.Header.Set("User-Agent", t.config.UserAgents[int(i)%len(t.config.UserAgents)])
+	}
+	for name, values := range t.config.Headers {
+		if len(req.Header.Values(name)) == 0 {
+			req.Header[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+	return t.base.RoundTrip(req)
+}
+
+// httpClient returns the shared client, which does not follow redirects
+func httpClient() *http.Client {
+	return getHTTPEngine().client
+}
+
+// httpSettings returns the settings of the shared HTTP engine
+func httpSettings() HTTPConfig {
+	return getHTTPEngine().config
+}
@@ -0,0 +1,123 @@
+package scanner
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SayerLinux/sub/pkg/utils"
+)
+
+func TestHTTPEngineReusesConnections(t *testing.T) {
+	var connections int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s|%s", r.Header.Get("User-Agent"), r.Header.Get("X-Team"))
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	ConfigureHTTP(HTTPConfig{
+		Timeout:         5 * time.Second,
+		MaxConnsPerHost: 2,
+		UserAgents:      []string{"sub-test"},
+		Headers:         http.Header{"X-Team": {"red"}},
+	})
+	defer ConfigureHTTP(DefaultHTTPConfig())
+
+	for i := 0; i < 20; i++ {
+		resp, err := fetchURL(fmt.Sprintf("%s/file%d", server.URL, i))
+		if err != nil {
+			t.Fatalf("request %d failed: %v", i, err)
+		}
+		if string(resp.Body) != "sub-test|red" {
+			t.Fatalf("configured headers not sent, server saw %q", resp.Body)
+		}
+	}
+
+	if n := atomic.LoadInt32(&connections); n != 1 {
+		t.Errorf("20 sequential requests opened %d connections, want 1", n)
+	}
+}
+
+func TestHTTPEngineRotatesUserAgentsAndSendsCookies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session, _ := r.Cookie("session")
+		if session != nil {
+			fmt.Fprintf(w, "%s|%s", r.Header.Get("User-Agent"), session.Value)
+		} else {
+			fmt.Fprintf(w, "%s|", r.Header.Get("User-Agent"))
+		}
+	}))
+	defer server.Close()
+
+	host, _, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	jar, _ := cookiejar.New(nil)
+	if err := readCookies(strings.NewReader("# Netscape HTTP Cookie File\n"+
+		host+"\tFALSE\t/\tFALSE\t0\tsession\tabc123\n"+
+		host+"\tFALSE\t/admin\tFALSE\t0\tadmin\tyes\n"), jar); err != nil {
+		t.Fatalf("readCookies failed: %v", err)
+	}
+
+	ConfigureHTTP(HTTPConfig{UserAgents: []string{"one", "two"}, Cookies: jar})
+	defer ConfigureHTTP(DefaultHTTPConfig())
+
+	var seen []string
+	for i := 0; i < 3; i++ {
+		resp, err := fetchURL(server.URL + "/")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		seen = append(seen, string(resp.Body))
+	}
+	want := []string{"one|abc123", "two|abc123", "one|abc123"}
+	if !reflect.DeepEqual(seen, want) {
+		t.Errorf("server saw %v, want %v", seen, want)
+	}
+
+	if err := readCookies(strings.NewReader("example.com\tTRUE\t/\n"), jar); err == nil {
+		t.Error("malformed cookie line accepted")
+	}
+}
+
+func TestHTTPHelpersUseEngine(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			http.Redirect(w, r, "/home", http.StatusFound)
+			return
+		}
+		fmt.Fprintf(w, "<html><head><title> %s %s </title></head></html>", r.Header.Get("User-Agent"), r.Header.Get("X-Team"))
+	}))
+	defer server.Close()
+
+	ConfigureHTTP(HTTPConfig{UserAgents: []string{"engine"}, Headers: http.Header{"X-Team": {"red"}}})
+	defer ConfigureHTTP(DefaultHTTPConfig())
+
+	// Redirects are reported, not followed
+	status, _, headers, err := utils.GetHTTPInfo(server.URL+"/", 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusFound || http.Header(headers).Get("Location") != "/home" {
+		t.Errorf("GetHTTPInfo() = %d, %v", status, headers)
+	}
+
+	title, err := utils.GetHTTPTitle(server.URL+"/home", 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title != "engine red" {
+		t.Errorf("GetHTTPTitle() = %q, want the engine user agent and headers", title)
+	}
+}
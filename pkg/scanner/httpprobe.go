@@ -0,0 +1,297 @@
+package scanner
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	// maxRedirects is the maximum number of redirects followed by the HTTP probe
+	maxRedirects = 10
+
+	// maxProbeBodySize is the maximum number of body bytes read for hashing and parsing
+	maxProbeBodySize = 1 << 20
+)
+
+// probeHeaders lists the response headers recorded on ServiceInfo
+var probeHeaders = []string{
+	"Server",
+	"X-Powered-By",
+	"X-AspNet-Version",
+	"X-Generator",
+	"Via",
+	"Location",
+	"Content-Security-Policy",
+	"Strict-Transport-Security",
+	"WWW-Authenticate",
+}
+
+// Redirect represents a single hop in an HTTP redirect chain
+type Redirect struct {
+	URL        string
+	StatusCode int
+	Location   string
+}
+
+// probeHTTP requests the service root, follows and records redirects and fills
+// the HTTP related fields of the service information
+func probeHTTP(info *ServiceInfo, isHTTPS bool) {
+	scheme := "http"
+	if isHTTPS {
+		scheme = "https"
+	}
+	info.URL = fmt.Sprintf("%s://%s:%d", scheme, info.Subdomain, info.Port)
+
+	// Redirects are followed manually so each hop can be recorded
+	client := httpClient()
+
+	resp, body, err := followRedirects(client, info)
+	if err != nil {
+		return
+	}
+
+	info.StatusCode = resp.StatusCode
+	info.Server = resp.Header.Get("Server")
+	info.ContentType = resp.Header.Get("Content-Type")
+	info.ContentLength = resp.ContentLength
+	if info.ContentLength < 0 {
+		info.ContentLength = int64(len(body))
+	}
+
+	sum := sha256.Sum256(body)
+	info.BodyHash = hex.EncodeToString(sum[:])
+
+	info.Headers = make(map[string]string)
+	for _, name := range probeHeaders {
+		if value := resp.Header.Get(name); value != "" {
+			info.Headers[name] = value
+		}
+	}
+
+	title, favicon := parseHTML(body)
+	info.Title = title
+
+	// Resolve the favicon against the final URL, falling back to /favicon.ico.
+	// The page can point it to any host, so out of scope icons are skipped.
+	base, err := url.Parse(info.FinalURL)
+	if err == nil {
+		if favicon == "" {
+			favicon = "/favicon.ico"
+		}
+		if ref, err := url.Parse(favicon); err == nil {
+			resolved := base.ResolveReference(ref)
+			if HostInScope(resolved.Hostname()) {
+				if hash, ok := fetchFaviconHash(client, resolved.String()); ok {
+					info.FaviconHash = hash
+				}
+			}
+		}
+	}
+
+	info.Technologies = DetectTechnologies(resp.Header, body)
+}
+
+// followRedirects requests info.URL and follows up to maxRedirects redirects,
+// recording each hop. It returns the final response along with its body.
+func followRedirects(client *http.Client, info *ServiceInfo) (*http.Response, []byte, error) {
+	current := info.URL
+
+	for i := 0; i <= maxRedirects; i++ {
+		resp, err := client.Get(current)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxProbeBodySize))
+		resp.Body.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		location := resp.Header.Get("Location")
+		if resp.StatusCode < 300 || resp.StatusCode >= 400 || location == "" {
+			info.FinalURL = current
+			return resp, body, nil
+		}
+
+		info.Redirects = append(info.Redirects, Redirect{
+			URL:        current,
+			StatusCode: resp.StatusCode,
+			Location:   location,
+		})
+
+		next, err := resp.Request.URL.Parse(location)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid redirect location %q: %v", location, err)
+		}
+
+		// Redirects leaving the scope are recorded but not followed
+		if !HostInScope(next.Hostname()) {
+			info.FinalURL = current
+			return resp, body, nil
+		}
+		current = next.String()
+	}
+
+	return nil, nil, fmt.Errorf("stopped after %d redirects", maxRedirects)
+}
+
+// parseHTML tokenizes an HTML document and returns its title and the favicon
+// reference declared by a <link rel="icon"> element, if any
+func parseHTML(body []byte) (string, string) {
+	var (
+		title   string
+		favicon string
+		inTitle bool
+		done    bool
+	)
+
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	for !done {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			done = true
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			switch token.Data {
+			case "title":
+				inTitle = title == ""
+			case "link":
+				if favicon == "" && isIconLink(token) {
+					favicon = attribute(token, "href")
+				}
+			}
+		case html.TextToken:
+			if inTitle {
+				title += string(tokenizer.Text())
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			switch string(name) {
+			case "title":
+				inTitle = false
+			case "head":
+				// Title and icons live in the head, nothing more to find
+				done = true
+			}
+		}
+	}
+
+	return strings.Join(strings.Fields(title), " "), favicon
+}
+
+// isIconLink reports whether a <link> token declares a favicon
+func isIconLink(token html.Token) bool {
+	for _, rel := range strings.Fields(strings.ToLower(attribute(token, "rel"))) {
+		if rel == "icon" {
+			return true
+		}
+	}
+	return false
+}
+
+// attribute returns the value of the named attribute of a token
+func attribute(token html.Token, name string) string {
+	for _, attr := range token.Attr {
+		if attr.Key == name {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// fetchFaviconHash downloads a favicon and returns its Shodan compatible
+// MurmurHash3 of the base64 encoded content
+func fetchFaviconHash(client *http.Client, faviconURL string) (int32, bool) {
+	resp, err := client.Get(faviconURL)
+	if err != nil {
+		return 0, false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, false
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxProbeBodySize))
+	if err != nil || len(data) == 0 {
+		return 0, false
+	}
+
+	return FaviconHash(data), true
+}
+
+// FaviconHash computes the favicon hash used by Shodan: the 32-bit MurmurHash3
+// of the base64 encoding with a newline after every 76 characters
+func FaviconHash(data []byte) int32 {
+	encoded := base64.StdEncoding.EncodeToString(data)
+
+	var sb strings.Builder
+	for len(encoded) > 76 {
+		sb.WriteString(encoded[:76])
+		sb.WriteByte('\n')
+		encoded = encoded[76:]
+	}
+	sb.WriteString(encoded)
+	sb.WriteByte('\n')
+
+	return int32(murmur3([]byte(sb.String()), 0))
+}
+
+// murmur3 computes the 32-bit MurmurHash3 of data
+func murmur3(data []byte, seed uint32) uint32 {
+	const (
+		c1 = 0xcc9e2d51
+		c2 = 0x1b873593
+	)
+
+	h := seed
+	length := len(data)
+	blocks := length / 4
+
+	for i := 0; i < blocks; i++ {
+		k := uint32(data[i*4]) | uint32(data[i*4+1])<<8 | uint32(data[i*4+2])<<16 | uint32(data[i*4+3])<<24
+		k *= c1
+		k = k<<15 | k>>17
+		k *= c2
+
+		h ^= k
+		h = h<<13 | h>>19
+		h = h*5 + 0xe6546b64
+	}
+
+	var k uint32
+	tail := data[blocks*4:]
+	switch len(tail) {
+	case 3:
+		k ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(tail[0])
+		k *= c1
+		k = k<<15 | k>>17
+		k *= c2
+		h ^= k
+	}
+
+	h ^= uint32(length)
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+
+	return h
+}
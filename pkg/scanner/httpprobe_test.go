@@ -0,0 +1,214 @@
+package scanner
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMurmur3(t *testing.T) {
+	tests := []struct {
+		data string
+		seed uint32
+		want uint32
+	}{
+		{"", 0, 0},
+		{"", 1, 0x514e28b7},
+		{"", 0xffffffff, 0x81f16f39},
+		{"\x00\x00\x00\x00", 0, 0x2362f9de},
+		{"aaaa", 0x9747b28c, 0x5a97808a},
+		{"Hello, world!", 0x9747b28c, 0x24884cba},
+		{"The quick brown fox jumps over the lazy dog", 0x9747b28c, 0x2fa826cd},
+	}
+
+	for _, test := range tests {
+		if got := murmur3([]byte(test.data), test.seed); got != test.want {
+			t.Errorf("murmur3(%q, %#x) = %#x, want %#x", test.data, test.seed, got, test.want)
+		}
+	}
+}
+
+func TestFaviconHash(t *testing.T) {
+	// Expected values are mmh3.hash(base64.encodebytes(data)), as computed by
+	// the Python tooling Shodan's favicon search is based on
+	sequence := make([]byte, 100)
+	for i := range sequence {
+		sequence[i] = byte(i)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want int32
+	}{
+		{"short", []byte{0, 0, 1, 0}, -216455174},
+		{"wrapped", sequence, -1165240594},
+	}
+
+	for _, test := range tests {
+		if got := FaviconHash(test.data); got != test.want {
+			t.Errorf("%s: FaviconHash() = %d, want %d", test.name, got, test.want)
+		}
+	}
+}
+
+func TestParseHTML(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		title   string
+		favicon string
+	}{
+		{"entities", `<html><head><title>Tom &amp; Jerry&#39;s &lt;Admin&gt;</title></head></html>`, "Tom & Jerry's <Admin>", ""},
+		{"whitespace", "<TITLE>\n   Login\n\tPortal  </TITLE>", "Login Portal", ""},
+		{"markup in title", `<title>A <b>bold</b> title</title>`, "A <b>bold</b> title", ""},
+		{"first title wins", `<title>One</title><title>Two</title>`, "One", ""},
+		{"unclosed", `<head><title>Unclosed`, "Unclosed", ""},
+		{"after head", `<head></head><body><title>Late</title></body>`, "", ""},
+		{"no title", `<p>Hello</p>`, "", ""},
+		{"icon", `<head><link rel="stylesheet" href="/a.css"><link rel="Shortcut Icon" href="/static/fav.png"></head>`, "", "/static/fav.png"},
+		{"unquoted icon", `<link rel=icon href=fav.ico><title>T</title>`, "T", "fav.ico"},
+	}
+
+	for _, test := range tests {
+		title, favicon := parseHTML([]byte(test.body))
+		if title != test.title || favicon != test.favicon {
+			t.Errorf("%s: parseHTML() = %q, %q, want %q, %q", test.name, title, favicon, test.title, test.favicon)
+		}
+	}
+}
+
+func TestFollowRedirectsStopsAtScope(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			http.Redirect(w, r, "/login", http.StatusFound)
+		case "/login":
+			// Same server under a name the scope excludes
+			http.Redirect(w, r, strings.Replace(server.URL, "127.0.0.1", "localhost", 1)+"/sso", http.StatusMovedPermanently)
+		default:
+			w.Write([]byte("sso"))
+		}
+	}))
+	defer server.Close()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	scope, err := parseScope(strings.NewReader("127.0.0.1\n!localhost\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scopeLock.Lock()
+	activeScope = scope
+	scopeLock.Unlock()
+	defer func() {
+		scopeLock.Lock()
+		activeScope = nil
+		scopeLock.Unlock()
+	}()
+
+	info := &ServiceInfo{URL: server.URL}
+	resp, _, err := followRedirects(client, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusMovedPermanently {
+		t.Errorf("StatusCode = %d, want %d", resp.StatusCode, http.StatusMovedPermanently)
+	}
+	if info.FinalURL != server.URL+"/login" {
+		t.Errorf("FinalURL = %q, want %q", info.FinalURL, server.URL+"/login")
+	}
+	if len(info.Redirects) != 2 || info.Redirects[1].StatusCode != http.StatusMovedPermanently {
+		t.Errorf("Redirects = %+v, want both hops recorded", info.Redirects)
+	}
+
+	// Without the exclusion the chain is followed to the end
+	scopeLock.Lock()
+	activeScope = nil
+	scopeLock.Unlock()
+
+	info = &ServiceInfo{URL: server.URL}
+	_, body, err := followRedirects(client, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(body, []byte("sso")) || !strings.HasSuffix(info.FinalURL, "/sso") {
+		t.Errorf("followRedirects() ended at %q with %q", info.FinalURL, body)
+	}
+}
+
+func TestProbeHTTPSkipsOutOfScopeFavicon(t *testing.T) {
+	var (
+		mutex     sync.Mutex
+		iconHost  string
+		iconHosts []string
+	)
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if r.URL.Path != "/" {
+			iconHosts = append(iconHosts, r.Host)
+			w.Write([]byte{0, 0, 1, 0})
+			return
+		}
+		icon := strings.Replace(server.URL, "127.0.0.1", iconHost, 1) + "/icon.ico"
+		w.Write([]byte(`<html><head><link rel="icon" href="` + icon + `"></head></html>`))
+	}))
+	defer server.Close()
+
+	scope, err := parseScope(strings.NewReader("127.0.0.1\n!localhost\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scopeLock.Lock()
+	activeScope = scope
+	scopeLock.Unlock()
+	defer func() {
+		scopeLock.Lock()
+		activeScope = nil
+		scopeLock.Unlock()
+	}()
+
+	_, portText, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	port, _ := strconv.Atoi(portText)
+
+	tests := []struct {
+		host    string
+		fetched bool
+		hash    int32
+	}{
+		// The same server under a name the scope excludes is never contacted
+		{"localhost", false, 0},
+		{"127.0.0.1", true, -216455174},
+	}
+	for _, test := range tests {
+		mutex.Lock()
+		iconHost, iconHosts = test.host, nil
+		mutex.Unlock()
+
+		info := &ServiceInfo{Subdomain: "127.0.0.1", Port: port}
+		probeHTTP(info, false)
+		if info.StatusCode != http.StatusOK {
+			t.Fatalf("%s: StatusCode = %d", test.host, info.StatusCode)
+		}
+
+		mutex.Lock()
+		fetched := len(iconHosts) > 0
+		mutex.Unlock()
+		if fetched != test.fetched {
+			t.Errorf("%s: icon fetched = %v, want %v", test.host, fetched, test.fetched)
+		}
+		if info.FaviconHash != test.hash {
+			t.Errorf("%s: FaviconHash = %d, want %d", test.host, info.FaviconHash, test.hash)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package scanner
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SourceNgram tags results generated by the n-gram model
+const SourceNgram = "ngram"
+
+const (
+	ngramStart     = "^"
+	ngramEnd       = "$"
+	ngramMaxTokens = 12
+
+	// ngramNeighbourWeight is the weight given to the numbers next to every
+	// number seen, so db3 also suggests db2 and db4
+	ngramNeighbourWeight = 0.25
+)
+
+// ngramWeights blend the trigram, bigram and unigram estimates
+var ngramWeights = [3]float64{0.6, 0.3, 0.1}
+
+// NameModel is a token trigram model of the labels in front of a domain.
+// Labels are split into runs of letters, runs of digits and single
+// separators, so prd-euw1-db3 becomes prd - euw 1 - db 3, and the model
+// learns which tokens follow which to suggest names in the same convention.
+type NameModel struct {
+	counts map[string]map[string]float64
+	totals map[string]float64
+	seen   map[string]bool
+}
+
+// NewNameModel creates an empty model
+func NewNameModel() *NameModel {
+	return &NameModel{
+		counts: make(map[string]map[string]float64),
+		totals: make(map[string]float64),
+		seen:   make(map[string]bool),
+	}
+}
+
+// tokenizeLabel splits a label into runs of letters, runs of digits and
+// other single characters
+func tokenizeLabel(label string) []string {
+	var tokens []string
+	start := 0
+	for i := 1; i <= len(label); i++ {
+		if i < len(label) && tokenClass(label[i]) == tokenClass(label[start]) && tokenClass(label[i]) != 0 {
+			continue
+		}
+		tokens = append(tokens, label[start:i])
+		start = i
+	}
+	return tokens
+}
+
+// tokenClass returns 1 for letters, 2 for digits and 0 for anything else
+func tokenClass(c byte) int {
+	switch {
+	case c >= 'a' && c <= 'z':
+		return 1
+	case c >= '0' && c <= '9':
+		return 2
+	}
+	return 0
+}
+
+// Train adds a label, the part of a name in front of the target domain, to
+// the model
+func (m *NameModel) Train(label string) {
+	label = strings.ToLower(strings.TrimSuffix(label, "."))
+	if label == "" || m.seen[label] {
+		return
+	}
+	m.seen[label] = true
+
+	tokens := append(append([]string{ngramStart, ngramStart}, tokenizeLabel(label)...), ngramEnd)
+	for i := 2; i < len(tokens); i++ {
+		m.add(tokens[i-2], tokens[i-1], tokens[i], 1)
+
+		// Numbers near the ones seen are likely to exist too
+		if number, err := strconv.Atoi(tokens[i]); err == nil {
+			for _, neighbour := range []int{number - 1, number + 1} {
+				if neighbour >= 0 {
+					m.add(tokens[i-2], tokens[i-1], fmt.Sprintf("%0*d", len(tokens[i]), neighbour), ngramNeighbourWeight)
+				}
+			}
+		}
+	}
+}
+
+// add counts a token after its two previous tokens, for each model order
+func (m *NameModel) add(first, second, token string, weight float64) {
+	for _, context := range []string{first + "\x00" + second, "\x00" + second, ""} {
+		if m.counts[context] == nil {
+			m.counts[context] = make(map[string]float64)
+		}
+		m.counts[context][token] += weight
+		m.totals[context] += weight
+	}
+}
+
+// probability estimates the probability of a token after two others
+func (m *NameModel) probability(first, second, token string) float64 {
+	p := 0.0
+	for i, context := range []string{first + "\x00" + second, "\x00" + second, ""} {
+		if total := m.totals[context]; total > 0 {
+			p += ngramWeights[i] * m.counts[context][token] / total
+		}
+	}
+	return p
+}
+
+// ngramState is a partial name during generation, with its last two tokens,
+// its number of tokens and its log probability
+type ngramState struct {
+	first  string
+	second string
+	length int
+	score  float64
+	name   string
+}
+
+// ngramQueue orders states from the most to the least probable
+type ngramQueue []*ngramState
+
+func (q ngramQueue) Len() int { return len(q) }
+func (q ngramQueue) Less(i, j int) bool {
+	if q[i].score != q[j].score {
+		return q[i].score > q[j].score
+	}
+	return q[i].name < q[j].name
+}
+func (q ngramQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *ngramQueue) Push(x interface{}) { *q = append(*q, x.(*ngramState)) }
+func (q *ngramQueue) Pop() interface{} {
+	old := *q
+	state := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return state
+}
+
+// Generate returns up to n of the most probable labels that were not
+// trained on, most probable first. Only labels that are valid DNS names are
+// returned, and names in exclude are skipped.
+func (m *NameModel) Generate(n int, exclude map[string]bool) []string {
+	if n <= 0 || len(m.seen) == 0 {
+		return nil
+	}
+
+	queue := &ngramQueue{{first: ngramStart, second: ngramStart}}
+	limit := 50*n + 1000
+	var names []string
+	emitted := make(map[string]bool)
+
+	// The search is bounded so sparse models cannot run for long
+	for expansions := 0; queue.Len() > 0 && len(names) < n && expansions < 200*n+1000; expansions++ {
+		state := heap.Pop(queue).(*ngramState)
+		if state.second == ngramEnd {
+			if !m.seen[state.name] && !exclude[state.name] && !emitted[state.name] && ValidateWord(state.name) == nil {
+				emitted[state.name] = true
+				names = append(names, state.name)
+			}
+			continue
+		}
+
+		for _, token := range m.continuations(state.second) {
+			if token != ngramEnd && state.length >= ngramMaxTokens {
+				continue
+			}
+			p := m.probability(state.first, state.second, token)
+			if p <= 0 {
+				continue
+			}
+
+			next := &ngramState{
+				first:  state.second,
+				second: token,
+				length: state.length + 1,
+				score:  state.score + math.Log(p),
+				name:   state.name,
+			}
+			if token != ngramEnd {
+				next.name += token
+			}
+			heap.Push(queue, next)
+		}
+
+		// Keep the most probable half once the queue grows too large
+		if queue.Len() > limit {
+			sort.Sort(queue)
+			*queue = (*queue)[:limit/2]
+			heap.Init(queue)
+		}
+	}
+
+	return names
+}
+
+// continuations returns the tokens seen after a token, in a stable order
+func (m *NameModel) continuations(token string) []string {
+	counts := m.counts["\x00"+token]
+	tokens := make([]string, 0, len(counts))
+	for next := range counts {
+		tokens = append(tokens, next)
+	}
+	sort.Strings(tokens)
+	return tokens
+}
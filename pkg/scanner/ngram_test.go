@@ -0,0 +1,62 @@
+package scanner
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenizeLabel(t *testing.T) {
+	tests := map[string][]string{
+		"prd-euw1-db3": {"prd", "-", "euw", "1", "-", "db", "3"},
+		"www":          {"www"},
+		"api.v2":       {"api", ".", "v", "2"},
+		"a--b":         {"a", "-", "-", "b"},
+	}
+	for label, want := range tests {
+		if got := tokenizeLabel(label); !reflect.DeepEqual(got, want) {
+			t.Errorf("tokenizeLabel(%q) = %v, want %v", label, got, want)
+		}
+	}
+}
+
+func TestNameModelGenerate(t *testing.T) {
+	model := NewNameModel()
+	for _, label := range []string{"prd-euw1-db1", "prd-euw1-db2", "prd-use1-db1", "stg-euw1-db1", "prd-euw1-web1", "prd-use1-web1"} {
+		model.Train(label)
+	}
+
+	names := model.Generate(20, map[string]bool{"prd-euw1-db3": true})
+	if len(names) != 20 {
+		t.Fatalf("generated %d names, want 20: %v", len(names), names)
+	}
+
+	generated := make(map[string]bool)
+	for _, name := range names {
+		if generated[name] {
+			t.Errorf("%s generated twice", name)
+		}
+		generated[name] = true
+		if model.seen[name] {
+			t.Errorf("%s was trained on", name)
+		}
+		if err := ValidateWord(name); err != nil {
+			t.Errorf("%s is not a valid label: %v", name, err)
+		}
+	}
+
+	// The naming convention is followed with new combinations and numbers
+	for _, want := range []string{"stg-euw1-db2", "prd-use1-db2"} {
+		if !generated[want] {
+			t.Errorf("%s not generated: %v", want, names)
+		}
+	}
+	if generated["prd-euw1-db3"] {
+		t.Error("excluded name generated")
+	}
+}
+
+func TestNameModelEmpty(t *testing.T) {
+	if names := NewNameModel().Generate(10, nil); names != nil {
+		t.Errorf("empty model generated %v", names)
+	}
+}
@@ -0,0 +1,290 @@
+package scanner
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// patternRange matches a numeric range such as 1..99 or 001..250
+var patternRange = regexp.MustCompile(`^(\d+)\.\.(\d+)$`)
+
+// patternCharsets are the mask placeholders, in the style of hashcat masks
+var patternCharsets = map[byte]string{
+	'l': "abcdefghijklmnopqrstuvwxyz",
+	'd': "0123456789",
+	'h': "0123456789abcdef",
+	'a': "abcdefghijklmnopqrstuvwxyz0123456789",
+}
+
+// patternPart is one position of a pattern, which takes each of its values
+// in turn
+type patternPart interface {
+	count() int
+	value(i int) string
+}
+
+// literalPart is fixed text
+type literalPart string
+
+func (p literalPart) count() int         { return 1 }
+func (p literalPart) value(i int) string { return string(p) }
+
+// charsetPart is a single character out of a set
+type charsetPart string
+
+func (p charsetPart) count() int         { return len(p) }
+func (p charsetPart) value(i int) string { return string(p[i]) }
+
+// rangePart is a number out of a range, padded with zeros to a width
+type rangePart struct {
+	start int
+	end   int
+	width int
+}
+
+func (p rangePart) count() int { return p.end - p.start + 1 }
+func (p rangePart) value(i int) string {
+	return fmt.Sprintf("%0*d", p.width, p.start+i)
+}
+
+// listPart is a word out of a list
+type listPart []string
+
+func (p listPart) count() int         { return len(p) }
+func (p listPart) value(i int) string { return p[i] }
+
+// Pattern generates candidate words from a pattern. Literal text is copied
+// as is, and the following placeholders are expanded:
+//
+//	?l ?d ?h ?a     a letter, digit, hex digit, or letter or digit
+//	[a-f0-9]        a character of a set
+//	{1..99}         a number of a range, zero padded as in {01..99}
+//	{dev,stage}     a word of a list
+//	{name}          a word of the word set of that name, or of the wordlist
+//	                file or built-in wordlist of that name
+//
+// Every combination is generated, the last placeholder changing fastest.
+// Words are generated one at a time, so the keyspace is never held in memory.
+type Pattern struct {
+	source  string
+	parts   []patternPart
+	indexes []int
+	word    string
+	started bool
+	done    bool
+}
+
+// ParsePattern parses a pattern. Word sets map names used in braces to the
+// wordlists holding their words.
+func ParsePattern(pattern string, wordSets map[string]string) (*Pattern, error) {
+	p := &Pattern{source: pattern}
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			p.parts = append(p.parts, literalPart(literal.String()))
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '\\':
+			if i+1 == len(pattern) {
+				return nil, fmt.Errorf("pattern %q ends with an escape", pattern)
+			}
+			i++
+			literal.WriteByte(pattern[i])
+		case '?':
+			if i+1 == len(pattern) {
+				return nil, fmt.Errorf("pattern %q ends with an incomplete mask", pattern)
+			}
+			i++
+			if pattern[i] == '?' {
+				literal.WriteByte('?')
+				continue
+			}
+			charset, ok := patternCharsets[pattern[i]]
+			if !ok {
+				return nil, fmt.Errorf("unknown mask ?%c in pattern %q (use ?l, ?d, ?h or ?a)", pattern[i], pattern)
+			}
+			flush()
+			p.parts = append(p.parts, charsetPart(charset))
+		case '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed [ in pattern %q", pattern)
+			}
+			charset, err := parseCharset(pattern[i+1 : i+end])
+			if err != nil {
+				return nil, fmt.Errorf("invalid set in pattern %q: %v", pattern, err)
+			}
+			flush()
+			p.parts = append(p.parts, charsetPart(charset))
+			i += end
+		case '{':
+			end := strings.IndexByte(pattern[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed { in pattern %q", pattern)
+			}
+			part, err := parseBraces(pattern[i+1:i+end], wordSets)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+			}
+			flush()
+			p.parts = append(p.parts, part)
+			i += end
+		default:
+			literal.WriteByte(c)
+		}
+	}
+	flush()
+
+	if len(p.parts) == 0 {
+		return nil, fmt.Errorf("empty pattern")
+	}
+	return p, nil
+}
+
+// parseCharset expands a character set such as a-z0-9_ into its characters
+func parseCharset(set string) (string, error) {
+	var chars strings.Builder
+	seen := make(map[byte]bool)
+	add := func(c byte) {
+		if !seen[c] {
+			seen[c] = true
+			chars.WriteByte(c)
+		}
+	}
+
+	for i := 0; i < len(set); i++ {
+		if i+2 < len(set) && set[i+1] == '-' {
+			if set[i] > set[i+2] {
+				return "", fmt.Errorf("range %s is reversed", set[i:i+3])
+			}
+			for c := set[i]; c <= set[i+2]; c++ {
+				add(c)
+			}
+			i += 2
+			continue
+		}
+		add(set[i])
+	}
+
+	if chars.Len() == 0 {
+		return "", fmt.Errorf("empty set []")
+	}
+	return chars.String(), nil
+}
+
+// parseBraces parses the content of braces: a numeric range, a list of
+// words or the name of a word set
+func parseBraces(content string, wordSets map[string]string) (patternPart, error) {
+	if match := patternRange.FindStringSubmatch(content); match != nil {
+		start, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid range {%s}", content)
+		}
+		end, err := strconv.Atoi(match[2])
+		if err != nil || end < start {
+			return nil, fmt.Errorf("invalid range {%s}", content)
+		}
+		width := 0
+		if len(match[1]) > 1 && match[1][0] == '0' {
+			width = len(match[1])
+		}
+		return rangePart{start: start, end: end, width: width}, nil
+	}
+
+	if strings.Contains(content, ",") {
+		return listPart(strings.Split(content, ",")), nil
+	}
+
+	if content == "" {
+		return nil, fmt.Errorf("empty braces {}")
+	}
+	wordlist, ok := wordSets[content]
+	if !ok {
+		wordlist = content
+	}
+	words, err := LoadWordlist(wordlist)
+	if err != nil {
+		if !ok {
+			return nil, fmt.Errorf("unknown word set {%s}: %v", content, err)
+		}
+		return nil, err
+	}
+	if len(words) == 0 {
+		return nil, fmt.Errorf("word set {%s} is empty", content)
+	}
+	return listPart(words), nil
+}
+
+// String returns the pattern as given
+func (p *Pattern) String() string {
+	return p.source
+}
+
+// Size returns the number of words the pattern generates, or math.MaxUint64
+// if there are more
+func (p *Pattern) Size() uint64 {
+	size := uint64(1)
+	for _, part := range p.parts {
+		count := uint64(part.count())
+		if size > math.MaxUint64/count {
+			return math.MaxUint64
+		}
+		size *= count
+	}
+	return size
+}
+
+// formatPatternSize formats the size of a pattern for display
+func formatPatternSize(size uint64) string {
+	if size == math.MaxUint64 {
+		return "more than " + strconv.FormatUint(size, 10)
+	}
+	return strconv.FormatUint(size, 10)
+}
+
+// Scan advances to the next generated word, returning false once every
+// combination was generated
+func (p *Pattern) Scan() bool {
+	if p.done {
+		return false
+	}
+
+	if !p.started {
+		p.started = true
+		p.indexes = make([]int, len(p.parts))
+	} else {
+		// Advance the last position, carrying over to the ones before it
+		i := len(p.parts) - 1
+		for ; i >= 0; i-- {
+			p.indexes[i]++
+			if p.indexes[i] < p.parts[i].count() {
+				break
+			}
+			p.indexes[i] = 0
+		}
+		if i < 0 {
+			p.done = true
+			return false
+		}
+	}
+
+	var word strings.Builder
+	for i, part := range p.parts {
+		word.WriteString(part.value(p.indexes[i]))
+	}
+	p.word = word.String()
+	return true
+}
+
+// Word returns the current generated word
+func (p *Pattern) Word() string {
+	return p.word
+}
@@ -0,0 +1,95 @@
+package scanner
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func generate(t *testing.T, pattern string, wordSets map[string]string) []string {
+	p, err := ParsePattern(pattern, wordSets)
+	if err != nil {
+		t.Fatalf("ParsePattern(%q) failed: %v", pattern, err)
+	}
+
+	var words []string
+	for p.Scan() {
+		words = append(words, p.Word())
+	}
+	if uint64(len(words)) != p.Size() {
+		t.Errorf("%q generated %d words, Size() = %d", pattern, len(words), p.Size())
+	}
+	return words
+}
+
+func TestPatternExpansion(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"web{01..03}", []string{"web01", "web02", "web03"}},
+		{"db{8..10}", []string{"db8", "db9", "db10"}},
+		{"dev-[a-c]", []string{"dev-a", "dev-b", "dev-c"}},
+		{"x[a-b0]", []string{"xa", "xb", "x0"}},
+		{`lit\{1..2\}??`, []string{"lit{1..2}?"}},
+	}
+
+	for _, test := range tests {
+		if got := generate(t, test.pattern, nil); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q = %v, want %v", test.pattern, got, test.want)
+		}
+	}
+
+	// The last placeholder changes fastest
+	got := generate(t, "{dev,prod}-api?d", nil)
+	if len(got) != 20 || got[0] != "dev-api0" || got[1] != "dev-api1" || got[10] != "prod-api0" {
+		t.Errorf("unexpected order: %v", got)
+	}
+}
+
+func TestPatternWordSets(t *testing.T) {
+	dir := t.TempDir()
+	envs := filepath.Join(dir, "envs.txt")
+	services := filepath.Join(dir, "services.txt")
+	os.WriteFile(envs, []byte("dev\nstage\n"), 0644)
+	os.WriteFile(services, []byte("api\n# comment\nauth\n"), 0644)
+
+	got := generate(t, "{env}-{service}", map[string]string{"env": envs, "service": services})
+	want := []string{"dev-api", "dev-auth", "stage-api", "stage-auth"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("word sets = %v, want %v", got, want)
+	}
+
+	// Built-in wordlists can be used by name
+	p, err := ParsePattern("{small}", nil)
+	if err != nil || p.Size() == 0 {
+		t.Errorf("built-in word set failed: %v", err)
+	}
+}
+
+func TestPatternErrors(t *testing.T) {
+	for _, pattern := range []string{"", "?x", "web?", "[a-", "[]", "[z-a]", "{1..", "{9..1}", "{}", "{missing}", `a\`} {
+		if _, err := ParsePattern(pattern, nil); err == nil {
+			t.Errorf("ParsePattern(%q) accepted an invalid pattern", pattern)
+		}
+	}
+}
+
+func TestPatternHugeKeyspace(t *testing.T) {
+	p, err := ParsePattern("?a?a?a?a?a?a?a?a?a?a?a?a?a", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Size() != math.MaxUint64 {
+		t.Errorf("Size() = %d, want saturation", p.Size())
+	}
+
+	// Words are generated lazily, so the first ones come at once
+	for i := 0; i < 3 && p.Scan(); i++ {
+	}
+	if p.Word() != "aaaaaaaaaaaac" {
+		t.Errorf("third word = %q", p.Word())
+	}
+}
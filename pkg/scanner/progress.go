@@ -0,0 +1,178 @@
+package scanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/SayerLinux/sub/pkg/utils"
+)
+
+// progressInterval is how often brute force progress is printed and the
+// resume file saved
+const progressInterval = 10 * time.Second
+
+// ResumeState records how far a brute force got through its wordlist, so an
+// interrupted run can continue from there
+type ResumeState struct {
+	Wordlist string   `json:"wordlist"`
+	Targets  []string `json:"targets"`
+	Offset   int64    `json:"offset"`
+}
+
+// LoadResumeState reads a resume file. A missing file yields nil.
+func LoadResumeState(path string) (*ResumeState, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read resume file: %v", err)
+	}
+
+	var state ResumeState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid resume file %s: %v", path, err)
+	}
+	return &state, nil
+}
+
+// Save writes the resume file, replacing it only once it is fully written
+func (r *ResumeState) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create resume directory: %v", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to save resume file: %v", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// Matches reports whether the state was saved by a scan of the same
+// wordlist and targets
+func (r *ResumeState) Matches(wordlist string, targets []string) bool {
+	return r.Wordlist == wordlist && reflect.DeepEqual(r.Targets, targets)
+}
+
+// wordlistProgress tracks how far the brute force has got through a streamed
+// wordlist. Workers finish words out of order, so the resume offset only
+// moves past a word once it and every word before it are done.
+type wordlistProgress struct {
+	reader  *utils.WordlistReader
+	mutex   sync.Mutex
+	pending map[int]int
+	ends    map[int]int64
+	next    int
+	lowest  int
+	offset  int64
+	read    float64
+	checked int64
+	start   int64
+	ended   bool
+}
+
+// newWordlistProgress starts tracking a wordlist from its current offset
+func newWordlistProgress(reader *utils.WordlistReader) *wordlistProgress {
+	return &wordlistProgress{
+		reader:  reader,
+		pending: make(map[int]int),
+		ends:    make(map[int]int64),
+		next:    1,
+		lowest:  1,
+		offset:  reader.Offset(),
+		read:    reader.Progress(),
+		start:   reader.Offset(),
+	}
+}
+
+// queue records a word read from the list that was turned into jobs and
+// returns its sequence number, given to each of its jobs
+func (p *wordlistProgress) queue(jobs int) int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	seq := p.next
+	p.next++
+	p.ends[seq] = p.reader.Offset()
+	p.read = p.reader.Progress()
+	p.pending[seq] = jobs
+	if jobs == 0 {
+		p.advance()
+	}
+	return seq
+}
+
+// done records a finished job of a word
+func (p *wordlistProgress) done(seq int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.checked++
+	p.pending[seq]--
+	if p.pending[seq] <= 0 {
+		p.advance()
+	}
+}
+
+// advance moves the resume offset past every finished word at the front
+func (p *wordlistProgress) advance() {
+	for p.lowest < p.next && p.pending[p.lowest] <= 0 {
+		p.offset = p.ends[p.lowest]
+		delete(p.pending, p.lowest)
+		delete(p.ends, p.lowest)
+		p.lowest++
+	}
+}
+
+// end records that the whole wordlist was read without an error
+func (p *wordlistProgress) end() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.ended = true
+}
+
+// complete reports whether the wordlist was read from its first word to its
+// last in this scan, rather than resumed or cut short
+func (p *wordlistProgress) complete() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.start == 0 && p.ended
+}
+
+// fraction returns the part of the wordlist read so far
+func (p *wordlistProgress) fraction() float64 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.read
+}
+
+// resumeOffset returns the offset every word before which is done
+func (p *wordlistProgress) resumeOffset() int64 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.offset
+}
+
+// status returns a line describing the progress, with an estimate of the
+// time left based on the part of the file read since the scan started
+func (p *wordlistProgress) status(start float64, elapsed time.Duration) string {
+	p.mutex.Lock()
+	checked, progress := p.checked, p.read
+	p.mutex.Unlock()
+
+	line := fmt.Sprintf("Progress: %.1f%% of wordlist, %d names checked", progress*100, checked)
+	if progress > start {
+		remaining := time.Duration(float64(elapsed) * (1 - progress) / (progress - start))
+		line += fmt.Sprintf(", ETA %s", remaining.Round(time.Second))
+	}
+	return line
+}
@@ -0,0 +1,129 @@
+package scanner
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SayerLinux/sub/pkg/utils"
+)
+
+func writeWordlists(t *testing.T, content string) map[string]string {
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "words.txt")
+	if err := os.WriteFile(plain, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	writer.Write([]byte(content))
+	writer.Close()
+	compressed := filepath.Join(dir, "words.gz")
+	if err := os.WriteFile(compressed, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return map[string]string{"plain": plain, "gzip": compressed}
+}
+
+func TestWordlistReaderResume(t *testing.T) {
+	for kind, path := range writeWordlists(t, "www\n# comment\r\napi\n\nmail\ndev") {
+		reader, err := utils.OpenWordlist(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reader.Compressed() != (kind == "gzip") {
+			t.Errorf("%s: Compressed() = %v", kind, reader.Compressed())
+		}
+
+		var words []string
+		var offset int64
+		for reader.Scan() {
+			words = append(words, reader.Word())
+			if reader.Word() == "api" {
+				offset = reader.Offset()
+			}
+		}
+		reader.Close()
+		if got := len(words); got != 4 || words[3] != "dev" {
+			t.Fatalf("%s: words = %v", kind, words)
+		}
+
+		reader, err = utils.OpenWordlist(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := reader.Skip(offset); err != nil {
+			t.Fatalf("%s: Skip failed: %v", kind, err)
+		}
+		if !reader.Scan() || reader.Word() != "mail" {
+			t.Errorf("%s: resumed at %q, want mail", kind, reader.Word())
+		}
+		reader.Close()
+	}
+}
+
+func TestWordlistProgressOffset(t *testing.T) {
+	paths := writeWordlists(t, "a\nb\nc\n")
+	reader, err := utils.OpenWordlist(paths["plain"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	progress := newWordlistProgress(reader)
+	var seqs []int
+	for reader.Scan() {
+		seqs = append(seqs, progress.queue(2))
+	}
+
+	// The offset only moves past words once all earlier words are done
+	progress.done(seqs[1])
+	progress.done(seqs[1])
+	progress.done(seqs[0])
+	if got := progress.resumeOffset(); got != 0 {
+		t.Errorf("offset = %d with a job of the first word pending, want 0", got)
+	}
+	progress.done(seqs[0])
+	if got := progress.resumeOffset(); got != 4 {
+		t.Errorf("offset = %d after two words, want 4", got)
+	}
+	progress.done(seqs[2])
+	progress.done(seqs[2])
+	if got := progress.resumeOffset(); got != 6 {
+		t.Errorf("offset = %d at the end, want 6", got)
+	}
+}
+
+func TestOpenWordlistResumeFile(t *testing.T) {
+	paths := writeWordlists(t, "www\napi\nmail\n")
+	resume := filepath.Join(t.TempDir(), "resume.json")
+	targets := []string{"example.com"}
+
+	state := &ResumeState{Wordlist: paths["gzip"], Targets: targets, Offset: 8}
+	if err := state.Save(resume); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewScanner(Config{Targets: targets, Wordlist: paths["gzip"], ResumeFile: resume})
+	if err := s.openWordlist(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.words.Close()
+	if !s.words.Scan() || s.words.Word() != "mail" {
+		t.Errorf("resumed at %q, want mail", s.words.Word())
+	}
+
+	// A resume file of another scan is ignored
+	other := NewScanner(Config{Targets: []string{"example.org"}, Wordlist: paths["gzip"], ResumeFile: resume})
+	if err := other.openWordlist(); err != nil {
+		t.Fatal(err)
+	}
+	defer other.words.Close()
+	if !other.words.Scan() || other.words.Word() != "www" {
+		t.Errorf("started at %q, want www", other.words.Word())
+	}
+}
@@ -0,0 +1,248 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// sourceFactories creates the known passive sources from their configuration.
+// A factory returns nil when the source cannot run, e.g. without an API key.
+var sourceFactories = map[string]func(client *http.Client, config SourceConfig) Source{
+	"crtsh": func(client *http.Client, config SourceConfig) Source {
+		return &CrtShSource{client: client, baseURL: baseURLOr(config, "https://crt.sh")}
+	},
+	"hackertarget": func(client *http.Client, config SourceConfig) Source {
+		return &HackerTargetSource{client: client, baseURL: baseURLOr(config, "https://api.hackertarget.com"), apiKey: config.APIKey}
+	},
+	"alienvault": func(client *http.Client, config SourceConfig) Source {
+		return &AlienVaultSource{client: client, baseURL: baseURLOr(config, "https://otx.alienvault.com"), apiKey: config.APIKey}
+	},
+	"securitytrails": func(client *http.Client, config SourceConfig) Source {
+		if config.APIKey == "" {
+			return nil
+		}
+		return &SecurityTrailsSource{client: client, baseURL: baseURLOr(config, "https://api.securitytrails.com"), apiKey: config.APIKey}
+	},
+	"wayback": func(client *http.Client, config SourceConfig) Source {
+		return &WaybackSource{client: client, baseURL: baseURLOr(config, "https://web.archive.org")}
+	},
+}
+
+// baseURLOr returns the configured base URL of a source or its default
+func baseURLOr(config SourceConfig, defaultURL string) string {
+	if config.BaseURL != "" {
+		return strings.TrimSuffix(config.BaseURL, "/")
+	}
+	return defaultURL
+}
+
+// CrtShSource searches certificate transparency logs through crt.sh
+type CrtShSource struct {
+	client  *http.Client
+	baseURL string
+}
+
+// Name returns the source name
+func (s *CrtShSource) Name() string {
+	return "crtsh"
+}
+
+// Enumerate returns the names found in certificates issued under domain
+func (s *CrtShSource) Enumerate(ctx context.Context, domain string) ([]string, error) {
+	query := fmt.Sprintf("%s/?q=%s&output=json", s.baseURL, url.QueryEscape("%."+domain))
+	body, err := fetchSource(ctx, s.client, query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []struct {
+		CommonName string `json:"common_name"`
+		NameValue  string `json:"name_value"`
+	}
+	if err := json.Unmarshal(body, &entries); err != nil {
+		return nil, fmt.Errorf("invalid response: %v", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.CommonName)
+		// name_value holds every SAN of the certificate, one per line
+		names = append(names, strings.Split(entry.NameValue, "\n")...)
+	}
+
+	return names, nil
+}
+
+// HackerTargetSource queries the HackerTarget host search DNS dataset
+type HackerTargetSource struct {
+	client  *http.Client
+	baseURL string
+	apiKey  string
+}
+
+// Name returns the source name
+func (s *HackerTargetSource) Name() string {
+	return "hackertarget"
+}
+
+// Enumerate returns the host names HackerTarget has seen under domain
+func (s *HackerTargetSource) Enumerate(ctx context.Context, domain string) ([]string, error) {
+	query := fmt.Sprintf("%s/hostsearch/?q=%s", s.baseURL, url.QueryEscape(domain))
+	if s.apiKey != "" {
+		query += "&apikey=" + url.QueryEscape(s.apiKey)
+	}
+
+	body, err := fetchSource(ctx, s.client, query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Errors are reported as plain text with a success status
+	if bytes.HasPrefix(body, []byte("error")) || bytes.HasPrefix(body, []byte("API count exceeded")) {
+		return nil, fmt.Errorf("%s", strings.TrimSpace(string(body)))
+	}
+
+	// Each line is "host,ip"
+	var names []string
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		host := strings.Split(scanner.Text(), ",")[0]
+		if host != "" {
+			names = append(names, host)
+		}
+	}
+
+	return names, scanner.Err()
+}
+
+// AlienVaultSource queries the AlienVault OTX passive DNS dataset
+type AlienVaultSource struct {
+	client  *http.Client
+	baseURL string
+	apiKey  string
+}
+
+// Name returns the source name
+func (s *AlienVaultSource) Name() string {
+	return "alienvault"
+}
+
+// Enumerate returns the host names OTX passive DNS has recorded under domain
+func (s *AlienVaultSource) Enumerate(ctx context.Context, domain string) ([]string, error) {
+	query := fmt.Sprintf("%s/api/v1/indicators/domain/%s/passive_dns", s.baseURL, url.PathEscape(domain))
+
+	var headers map[string]string
+	if s.apiKey != "" {
+		headers = map[string]string{"X-OTX-API-KEY": s.apiKey}
+	}
+
+	body, err := fetchSource(ctx, s.client, query, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		PassiveDNS []struct {
+			Hostname string `json:"hostname"`
+		} `json:"passive_dns"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("invalid response: %v", err)
+	}
+
+	var names []string
+	for _, record := range response.PassiveDNS {
+		names = append(names, record.Hostname)
+	}
+
+	return names, nil
+}
+
+// SecurityTrailsSource queries the SecurityTrails subdomain API
+type SecurityTrailsSource struct {
+	client  *http.Client
+	baseURL string
+	apiKey  string
+}
+
+// Name returns the source name
+func (s *SecurityTrailsSource) Name() string {
+	return "securitytrails"
+}
+
+// Enumerate returns the subdomains SecurityTrails knows for domain
+func (s *SecurityTrailsSource) Enumerate(ctx context.Context, domain string) ([]string, error) {
+	query := fmt.Sprintf("%s/v1/domain/%s/subdomains", s.baseURL, url.PathEscape(domain))
+	body, err := fetchSource(ctx, s.client, query, map[string]string{"APIKEY": s.apiKey})
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Subdomains []string `json:"subdomains"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("invalid response: %v", err)
+	}
+
+	// The API returns labels relative to the domain
+	names := make([]string, 0, len(response.Subdomains))
+	for _, label := range response.Subdomains {
+		names = append(names, label+"."+domain)
+	}
+
+	return names, nil
+}
+
+// WaybackSource extracts host names from URLs captured by the Wayback Machine
+type WaybackSource struct {
+	client  *http.Client
+	baseURL string
+}
+
+// Name returns the source name
+func (s *WaybackSource) Name() string {
+	return "wayback"
+}
+
+// Enumerate returns the hosts of archived URLs under domain
+func (s *WaybackSource) Enumerate(ctx context.Context, domain string) ([]string, error) {
+	query := fmt.Sprintf("%s/cdx/search/cdx?url=%s&output=txt&fl=original&collapse=urlkey", s.baseURL, url.QueryEscape("*."+domain+"/*"))
+	body, err := fetchSource(ctx, s.client, query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if !strings.Contains(line, "://") {
+			line = "http://" + line
+		}
+
+		parsed, err := url.Parse(line)
+		if err != nil {
+			continue
+		}
+		host := parsed.Hostname()
+		if host != "" && !seen[host] {
+			seen[host] = true
+			names = append(names, host)
+		}
+	}
+
+	return names, scanner.Err()
+}
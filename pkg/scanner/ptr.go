@@ -0,0 +1,170 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/SayerLinux/sub/pkg/utils"
+)
+
+// maxRangeAddresses is the largest address range swept with PTR lookups
+const maxRangeAddresses = 1 << 20
+
+// SourcePTR tags results found through reverse DNS
+const SourcePTR = "ptr"
+
+// ParseRange parses a CIDR target such as 203.0.113.0/24, returning nil if
+// the target is not a CIDR and an error if the range is too large to sweep
+func ParseRange(target string) (*net.IPNet, error) {
+	_, network, err := net.ParseCIDR(target)
+	if err != nil {
+		return nil, nil
+	}
+
+	ones, bits := network.Mask.Size()
+	if bits-ones > 20 {
+		return nil, fmt.Errorf("range %s has more than %d addresses", target, maxRangeAddresses)
+	}
+	return network, nil
+}
+
+// eachAddress calls fn for every address of a network
+func eachAddress(network *net.IPNet, fn func(ip net.IP)) {
+	ip := network.IP.Mask(network.Mask)
+	for ; network.Contains(ip); ip = offsetIP(ip, 1) {
+		fn(ip)
+		if ip.Equal(offsetIP(ip, 1)) {
+			return
+		}
+	}
+}
+
+// NeighbourIPs returns the addresses from ip-n to ip+n, ip included
+func NeighbourIPs(address string, n int) []string {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return nil
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+
+	var neighbours []string
+	for i := -n; i <= n; i++ {
+		neighbour := offsetIP(ip, int64(i))
+		// Offsets past the start or end of the address space are clamped
+		if i != 0 && neighbour.Equal(ip) {
+			continue
+		}
+		neighbours = append(neighbours, neighbour.String())
+	}
+	return neighbours
+}
+
+// offsetIP returns ip moved by delta addresses, clamped to the address space
+func offsetIP(ip net.IP, delta int64) net.IP {
+	value := new(big.Int).SetBytes(ip)
+	value.Add(value, big.NewInt(delta))
+
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(len(ip)*8))
+	if value.Sign() < 0 || value.Cmp(limit) >= 0 {
+		return ip
+	}
+
+	result := make(net.IP, len(ip))
+	value.FillBytes(result)
+	return result
+}
+
+// sendRangeJobs queues a PTR lookup for every address of the target range
+func (s *Scanner) sendRangeJobs(jobs chan<- scanJob) {
+	eachAddress(s.network, func(ip net.IP) {
+		jobs <- scanJob{address: ip.String()}
+	})
+}
+
+// sendSweepJobs queues a PTR lookup for the addresses around every IP found
+func (s *Scanner) sendSweepJobs(jobs chan<- scanJob) {
+	s.mutex.Lock()
+	var found []string
+	for ip := range s.foundIPs {
+		found = append(found, ip)
+	}
+	s.mutex.Unlock()
+	sort.Strings(found)
+
+	queued := make(map[string]bool)
+	for _, ip := range found {
+		for _, neighbour := range NeighbourIPs(ip, s.config.PTRSweep) {
+			if !queued[neighbour] {
+				queued[neighbour] = true
+				jobs <- scanJob{address: neighbour}
+			}
+		}
+	}
+	fmt.Printf("\033[1;34m[*] Swept %d addresses around %d IPs\033[0m\n", len(queued), len(found))
+}
+
+// checkAddress looks up the PTR names of an address and reports those in
+// scope that were not already found
+func (s *Scanner) checkAddress(address string) {
+	names, _ := utils.Resolver().LookupAddr(context.Background(), address)
+
+	reported := false
+	for _, name := range names {
+		name = normaliseName(name)
+		if !s.inDomains(name) {
+			continue
+		}
+		if !InScope(name) {
+			logOutOfScope(name)
+			continue
+		}
+
+		s.mutex.Lock()
+		seen := s.foundNames[name]
+		s.foundNames[name] = true
+		s.mutex.Unlock()
+		if seen {
+			continue
+		}
+
+		reported = true
+		s.resultChan <- ScanResult{
+			Subdomain:   name,
+			Domain:      s.apexOf(name),
+			IP:          address,
+			Found:       true,
+			Sources:     []string{SourcePTR},
+			Network:     s.config.IPDatabase.Lookup(address),
+			Attribution: Attribute(address),
+		}
+	}
+
+	if !reported {
+		s.resultChan <- ScanResult{Subdomain: address}
+	}
+}
+
+// inDomains reports whether a PTR name is under one of the report domains.
+// When sweeping a range without report domains every name is reported.
+func (s *Scanner) inDomains(name string) bool {
+	domains := s.config.Domains
+	if len(domains) == 0 {
+		if s.network != nil {
+			return name != ""
+		}
+		domains = s.targets()
+	}
+
+	for _, domain := range domains {
+		if isUnderDomain(name, strings.ToLower(strings.TrimSuffix(domain, "."))) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package scanner
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestNeighbourIPs(t *testing.T) {
+	tests := []struct {
+		ip   string
+		n    int
+		want []string
+	}{
+		{"192.0.2.10", 2, []string{"192.0.2.8", "192.0.2.9", "192.0.2.10", "192.0.2.11", "192.0.2.12"}},
+		{"192.0.2.255", 1, []string{"192.0.2.254", "192.0.2.255", "192.0.3.0"}},
+		{"0.0.0.0", 1, []string{"0.0.0.0", "0.0.0.1"}},
+		{"2001:db8::1", 1, []string{"2001:db8::", "2001:db8::1", "2001:db8::2"}},
+		{"not an ip", 1, nil},
+	}
+
+	for _, test := range tests {
+		if got := NeighbourIPs(test.ip, test.n); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("NeighbourIPs(%s, %d) = %v, want %v", test.ip, test.n, got, test.want)
+		}
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	network, err := ParseRange("203.0.113.5/30")
+	if err != nil || network == nil {
+		t.Fatalf("ParseRange failed: %v", err)
+	}
+
+	var addresses []string
+	eachAddress(network, func(ip net.IP) {
+		addresses = append(addresses, ip.String())
+	})
+	if want := []string{"203.0.113.4", "203.0.113.5", "203.0.113.6", "203.0.113.7"}; !reflect.DeepEqual(addresses, want) {
+		t.Errorf("addresses = %v, want %v", addresses, want)
+	}
+
+	if network, _ := ParseRange("example.com"); network != nil {
+		t.Error("domain parsed as a range")
+	}
+	if _, err := ParseRange("10.0.0.0/8"); err == nil {
+		t.Error("oversized range accepted")
+	}
+}
@@ -1,6 +1,7 @@
 package scanner
 
 import (
+	"encoding/json"
 	"fmt"
 	"path/filepath"
 	"strings"
@@ -12,10 +13,13 @@ import (
 
 // Result represents a subdomain scan result
 type Result struct {
-	Subdomain string
-	IP        string
-	Found     bool
-	Timestamp time.Time
+	Subdomain   string       `json:"subdomain"`
+	IP          string       `json:"ip"`
+	Found       bool         `json:"-"`
+	Sources     []string     `json:"sources,omitempty"`
+	Network     *IPInfo      `json:"network,omitempty"`
+	Attribution *Attribution `json:"attribution,omitempty"`
+	Timestamp   time.Time    `json:"timestamp"`
 }
 
 // ServiceResult represents a service scan result
@@ -30,9 +34,21 @@ type ServiceResult struct {
 // FileResult represents a file extraction result
 type FileResult struct {
 	Subdomain string
+	URL       string
 	FilePath  string
 	Success   bool
 	Size      int64
+	Hash      string
+	Timestamp time.Time
+}
+
+// SecretResult represents a secret found in an extracted file
+type SecretResult struct {
+	Subdomain string
+	FilePath  string
+	Line      int
+	RuleID    string
+	Secret    string
 	Timestamp time.Time
 }
 
@@ -41,7 +57,9 @@ type ResultManager struct {
 	results        []Result
 	serviceResults []ServiceResult
 	fileResults    []FileResult
+	secretResults  []SecretResult
 	outputPath     string
+	outputFormat   string
 	outputDir      string
 	logger         *utils.Logger
 	mutex          sync.Mutex
@@ -53,26 +71,50 @@ func NewResultManager(outputPath string, outputDir string, logger *utils.Logger)
 		results:        []Result{},
 		serviceResults: []ServiceResult{},
 		fileResults:    []FileResult{},
+		secretResults:  []SecretResult{},
 		outputPath:     outputPath,
 		outputDir:      outputDir,
 		logger:         logger,
 	}
 }
 
+// SetOutputFormat sets the format of the subdomain results file, "csv" (the
+// default) or "json"
+func (rm *ResultManager) SetOutputFormat(format string) {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+
+	rm.outputFormat = format
+}
+
 // AddResult adds a subdomain result
 func (rm *ResultManager) AddResult(subdomain string, ip string, found bool) {
+	rm.AddSourcedResult(subdomain, ip, found, nil)
+}
+
+// AddSourcedResult adds a subdomain result tagged with the sources that reported it
+func (rm *ResultManager) AddSourcedResult(subdomain string, ip string, found bool, sources []string) {
+	rm.AddScanResult(ScanResult{Subdomain: subdomain, IP: ip, Found: found, Sources: sources})
+}
+
+// AddScanResult adds a result produced by the scanner, keeping its sources
+// and network information
+func (rm *ResultManager) AddScanResult(scanResult ScanResult) {
 	rm.mutex.Lock()
 	defer rm.mutex.Unlock()
 
 	result := Result{
-		Subdomain: subdomain,
-		IP:        ip,
-		Found:     found,
-		Timestamp: time.Now(),
+		Subdomain:   scanResult.Subdomain,
+		IP:          scanResult.IP,
+		Found:       scanResult.Found,
+		Sources:     scanResult.Sources,
+		Network:     scanResult.Network,
+		Attribution: scanResult.Attribution,
+		Timestamp:   time.Now(),
 	}
 
 	rm.results = append(rm.results, result)
-	rm.logger.Result(subdomain, found, ip)
+	rm.logger.Result(scanResult.Subdomain, scanResult.Found, scanResult.IP)
 }
 
 // AddServiceResult adds a service result
@@ -93,20 +135,40 @@ func (rm *ResultManager) AddServiceResult(subdomain string, port int, service st
 }
 
 // AddFileResult adds a file extraction result
-func (rm *ResultManager) AddFileResult(subdomain string, filePath string, success bool, size int64) {
+func (rm *ResultManager) AddFileResult(subdomain string, url string, filePath string, success bool, size int64, hash string) {
 	rm.mutex.Lock()
 	defer rm.mutex.Unlock()
 
 	result := FileResult{
 		Subdomain: subdomain,
+		URL:       url,
 		FilePath:  filePath,
 		Success:   success,
 		Size:      size,
+		Hash:      hash,
 		Timestamp: time.Now(),
 	}
 
 	rm.fileResults = append(rm.fileResults, result)
-	rm.logger.FileResult(subdomain, filePath, success, size)
+	rm.logger.FileResult(subdomain, url, success, size)
+}
+
+// AddSecretResult adds a secret found in an extracted file
+func (rm *ResultManager) AddSecretResult(subdomain string, finding SecretFinding) {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+
+	result := SecretResult{
+		Subdomain: subdomain,
+		FilePath:  finding.File,
+		Line:      finding.Line,
+		RuleID:    finding.RuleID,
+		Secret:    finding.Secret,
+		Timestamp: time.Now(),
+	}
+
+	rm.secretResults = append(rm.secretResults, result)
+	rm.logger.SecretResult(subdomain, finding.File, finding.Line, finding.RuleID, finding.Secret)
 }
 
 // GetResults returns all subdomain results
@@ -141,6 +203,26 @@ func (rm *ResultManager) SaveResults() error {
 	rm.mutex.Lock()
 	defer rm.mutex.Unlock()
 
+	if rm.outputFormat == "json" {
+		found := []Result{}
+		for _, result := range rm.results {
+			if result.Found {
+				found = append(found, result)
+			}
+		}
+
+		data, err := json.MarshalIndent(found, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode results: %v", err)
+		}
+		if err := utils.SaveToFile(rm.outputPath, string(data)+"\n"); err != nil {
+			return err
+		}
+
+		rm.logger.Success("Results saved to %s", rm.outputPath)
+		return nil
+	}
+
 	// Create the output file
 	file, err := utils.CreateOutputFile(rm.outputPath, "# Sub Tool Results - Generated on "+time.Now().Format("2006-01-02 15:04:05"))
 	if err != nil {
@@ -151,7 +233,11 @@ func (rm *ResultManager) SaveResults() error {
 	// Write found subdomains to the file
 	for _, result := range rm.results {
 		if result.Found {
-			_, err := fmt.Fprintf(file, "%s,%s\n", result.Subdomain, result.IP)
+			line := fmt.Sprintf("%s,%s", result.Subdomain, result.IP)
+			if len(result.Sources) > 0 {
+				line += "," + strings.Join(result.Sources, "|")
+			}
+			_, err := fmt.Fprintln(file, line)
 			if err != nil {
 				return fmt.Errorf("failed to write to output file: %v", err)
 			}
@@ -221,7 +307,7 @@ func (rm *ResultManager) SaveFileResults() error {
 	// Write file results to the file
 	for _, result := range rm.fileResults {
 		if result.Success {
-			_, err := fmt.Fprintf(file, "%s,%s,%d\n", result.Subdomain, result.FilePath, result.Size)
+			_, err := fmt.Fprintf(file, "%s,%s,%s,%d,%s\n", result.Subdomain, result.URL, result.FilePath, result.Size, result.Hash)
 			if err != nil {
 				return fmt.Errorf("failed to write to files file: %v", err)
 			}
@@ -232,6 +318,39 @@ func (rm *ResultManager) SaveFileResults() error {
 	return nil
 }
 
+// SaveSecretResults saves the secrets found in extracted files
+func (rm *ResultManager) SaveSecretResults() error {
+	if rm.outputDir == "" {
+		return nil
+	}
+
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+
+	if len(rm.secretResults) == 0 {
+		return nil
+	}
+
+	// Create the secrets output file
+	secretsPath := filepath.Join(rm.outputDir, "secrets.txt")
+	file, err := utils.CreateOutputFile(secretsPath, "# Sub Tool Secret Scan Results - Generated on "+time.Now().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Write secret results to the file
+	for _, result := range rm.secretResults {
+		_, err := fmt.Fprintf(file, "%s,%s:%d,%s,%s\n", result.Subdomain, result.FilePath, result.Line, result.RuleID, result.Secret)
+		if err != nil {
+			return fmt.Errorf("failed to write to secrets file: %v", err)
+		}
+	}
+
+	rm.logger.Success("Secret results saved to %s", secretsPath)
+	return nil
+}
+
 // SaveAllResults saves all results
 func (rm *ResultManager) SaveAllResults() error {
 	// Save subdomain results
@@ -249,6 +368,11 @@ func (rm *ResultManager) SaveAllResults() error {
 		return err
 	}
 
+	// Save secret results
+	if err := rm.SaveSecretResults(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -273,6 +397,21 @@ func (rm *ResultManager) GenerateSummary() string {
 	sb.WriteString(fmt.Sprintf("Subdomains found: %d\n", foundCount))
 	sb.WriteString(fmt.Sprintf("Services discovered: %d\n", len(rm.serviceResults)))
 	sb.WriteString(fmt.Sprintf("Files extracted: %d\n", len(rm.fileResults)))
+	sb.WriteString(fmt.Sprintf("Secrets found: %d\n", len(rm.secretResults)))
+
+	// Group hosts by network owner when they were enriched
+	networks := make(map[string]*IPInfo)
+	enriched := false
+	for _, result := range rm.results {
+		if result.Found {
+			networks[result.Subdomain] = result.Network
+			enriched = enriched || result.Network != nil
+		}
+	}
+	if enriched {
+		sb.WriteString("Hosts by network owner:\n")
+		sb.WriteString(FormatNetworkGroups(GroupByNetwork(networks)))
+	}
 
 	// Add output file information
 	if rm.outputPath != "" {
@@ -282,6 +421,9 @@ func (rm *ResultManager) GenerateSummary() string {
 	if rm.outputDir != "" {
 		sb.WriteString(fmt.Sprintf("Service results saved to: %s\n", filepath.Join(rm.outputDir, "services.txt")))
 		sb.WriteString(fmt.Sprintf("File results saved to: %s\n", filepath.Join(rm.outputDir, "files.txt")))
+		if len(rm.secretResults) > 0 {
+			sb.WriteString(fmt.Sprintf("Secret results saved to: %s\n", filepath.Join(rm.outputDir, "secrets.txt")))
+		}
 	}
 
 	return sb.String()
@@ -0,0 +1,135 @@
+package scanner
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/SayerLinux/sub/pkg/utils"
+)
+
+func TestResultManagerJSONRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.json")
+	rm := NewResultManager(path, "", utils.NewLogger(false, nil))
+	rm.SetOutputFormat("json")
+
+	rm.AddScanResult(ScanResult{
+		Subdomain: "www.example.com",
+		IP:        "192.0.2.1",
+		Found:     true,
+		Sources:   []string{"bruteforce", "crtsh"},
+		Network:   &IPInfo{ASN: 64500, Organisation: "Example Hosting", Country: "NL"},
+	})
+	rm.AddResult("missing.example.com", "", false)
+	rm.AddResult("mail.example.com", "192.0.2.2", true)
+	if err := rm.SaveResults(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The schema: found results only, optional fields left out when empty
+	var raw []map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, data)
+	}
+	if len(raw) != 2 {
+		t.Fatalf("got %d results, want 2", len(raw))
+	}
+	wantKeys := [][]string{
+		{"ip", "network", "sources", "subdomain", "timestamp"},
+		{"ip", "subdomain", "timestamp"},
+	}
+	for i, object := range raw {
+		var keys []string
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		if !reflect.DeepEqual(keys, wantKeys[i]) {
+			t.Errorf("result %d has keys %v, want %v", i, keys, wantKeys[i])
+		}
+	}
+	network, _ := raw[0]["network"].(map[string]interface{})
+	if network["asn"] != float64(64500) || network["organisation"] != "Example Hosting" || network["country"] != "NL" {
+		t.Errorf("network = %v", raw[0]["network"])
+	}
+
+	var results []Result
+	if err := json.Unmarshal(data, &results); err != nil {
+		t.Fatal(err)
+	}
+	saved := rm.GetFoundResults()
+	for i := range results {
+		results[i].Found = true
+		if !results[i].Timestamp.Equal(saved[i].Timestamp) {
+			t.Errorf("result %d timestamp = %v, want %v", i, results[i].Timestamp, saved[i].Timestamp)
+		}
+		results[i].Timestamp = saved[i].Timestamp
+	}
+	if !reflect.DeepEqual(results, saved) {
+		t.Errorf("round trip = %+v, want %+v", results, saved)
+	}
+}
+
+func TestResultManagerJSONEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.json")
+	rm := NewResultManager(path, "", utils.NewLogger(false, nil))
+	rm.SetOutputFormat("json")
+	rm.AddResult("missing.example.com", "", false)
+	if err := rm.SaveResults(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "[]\n" {
+		t.Errorf("SaveResults() wrote %q, want an empty array", data)
+	}
+}
+
+func TestScannerResultsClosedAfterStart(t *testing.T) {
+	s := NewScanner(Config{Target: "example.invalid", Threads: 2})
+	s.AddCandidate("www.example.invalid", "test")
+	s.AddCandidate("mail.example.invalid", "test")
+
+	var received []ScanResult
+	closed := make(chan struct{})
+	stream := s.Results()
+	go func() {
+		defer close(closed)
+		for result := range stream {
+			received = append(received, result)
+		}
+	}()
+
+	s.Start()
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Results() not closed after Start returned")
+	}
+	if len(received) != 2 {
+		t.Fatalf("received %d results, want 2", len(received))
+	}
+	for _, result := range received {
+		if !reflect.DeepEqual(result.Sources, []string{"test"}) || result.Domain != "example.invalid" {
+			t.Errorf("result = %+v", result)
+		}
+	}
+
+	// Misses are streamed but not kept
+	if len(s.results) != 0 {
+		t.Errorf("scanner kept %d results, want only the found ones", len(s.results))
+	}
+}
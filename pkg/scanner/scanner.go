@@ -2,38 +2,84 @@ package scanner
 
 import (
 	"bufio"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"os"
+	"os/signal"
+	"sort"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
+	"github.com/SayerLinux/sub/pkg/utils"
 	"github.com/fatih/color"
 )
 
 // Config holds the scanner configuration
 type Config struct {
-	Target     string
-	Wordlist   string
-	Threads    int
-	OutputFile string
-	Verbose    bool
+	Target       string
+	Targets      []string
+	Wordlist     string
+	Patterns     []*Pattern
+	Threads      int
+	OutputFile   string
+	OutputFormat string
+	Verbose      bool
+	PTRSweep     int
+	Domains      []string
+	IPDatabase   *IPDatabase
+	ResumeFile   string
+	Learn        int
+	LearnCorpus  string
+	HitStats     string
 }
 
 // ScanResult represents a scan result
 type ScanResult struct {
-	Subdomain string
-	IP        string
-	Found     bool
+	Subdomain   string       `json:"subdomain"`
+	Domain      string       `json:"domain,omitempty"`
+	IP          string       `json:"ip"`
+	Found       bool         `json:"-"`
+	Sources     []string     `json:"sources,omitempty"`
+	Network     *IPInfo      `json:"network,omitempty"`
+	Attribution *Attribution `json:"attribution,omitempty"`
+}
+
+// SourceBruteForce tags results that come from the wordlist
+const SourceBruteForce = "bruteforce"
+
+// scanJob is a name to resolve along with the sources that suggested it, or
+// an address to look up the PTR names of. Entry marks names made from a
+// wordlist entry, whose hits are recorded in the hit statistics.
+type scanJob struct {
+	subdomain string
+	sources   []string
+	address   string
+	word      int
+	entry     bool
 }
 
 // Scanner represents the subdomain scanner
 type Scanner struct {
 	config     Config
 	results    []ScanResult
-	wordlist   []string
+	words      *utils.WordlistReader
+	progress   *wordlistProgress
+	corpus     []string
+	candidates []scanJob
+	candidate  map[string]int
+	network    *net.IPNet
+	foundIPs   map[string]bool
+	foundNames map[string]bool
+	hits       map[string]int
+	wildcards  map[string]map[string]bool
 	resultChan chan ScanResult
+	stream     chan ScanResult
+	collected  chan struct{}
 	wg         sync.WaitGroup
 	mutex      sync.Mutex
 }
@@ -43,21 +89,123 @@ func NewScanner(config Config) *Scanner {
 	return &Scanner{
 		config:     config,
 		results:    []ScanResult{},
+		candidate:  make(map[string]int),
+		foundIPs:   make(map[string]bool),
+		foundNames: make(map[string]bool),
+		hits:       make(map[string]int),
 		resultChan: make(chan ScanResult),
 	}
 }
 
-// Start begins the scanning process
+// AddCandidate queues a full subdomain name found by another discovery method
+// to be resolved along with the wordlist, tagged with the sources that
+// reported it. It must be called before Start.
+func (s *Scanner) AddCandidate(subdomain string, sources ...string) {
+	subdomain = strings.ToLower(strings.TrimSuffix(subdomain, "."))
+	if i, ok := s.candidate[subdomain]; ok {
+		for _, source := range sources {
+			if !containsString(s.candidates[i].sources, source) {
+				s.candidates[i].sources = append(s.candidates[i].sources, source)
+			}
+		}
+		return
+	}
+
+	s.candidate[subdomain] = len(s.candidates)
+	s.candidates = append(s.candidates, scanJob{subdomain: subdomain, sources: sources})
+}
+
+// Results returns a channel that receives every checked subdomain as soon as
+// it is resolved, so later stages can process hits while the scan runs. The
+// channel is closed when the scan completes. When it is used, results are not
+// printed by the scanner and must be consumed, or the scan blocks.
+func (s *Scanner) Results() <-chan ScanResult {
+	if s.stream == nil {
+		s.stream = make(chan ScanResult, s.config.Threads)
+	}
+	return s.stream
+}
+
+// targets returns the domains brute forced by the scan. Several domains can
+// be given in Targets to share one wordlist and worker pool.
+func (s *Scanner) targets() []string {
+	if len(s.config.Targets) > 0 {
+		return s.config.Targets
+	}
+	return []string{s.config.Target}
+}
+
+// apexOf returns the target domain a name is under, or an empty string if it
+// is under none of them
+func (s *Scanner) apexOf(name string) string {
+	apex := ""
+	for _, target := range s.targets() {
+		target = strings.ToLower(target)
+		if isUnderDomain(name, target) && len(target) > len(apex) {
+			apex = target
+		}
+	}
+	return apex
+}
+
+// Start begins the scanning process. A CIDR target is swept with PTR
+// lookups instead of being brute forced.
 func (s *Scanner) Start() {
-	// Load wordlist
-	err := s.loadWordlist()
+	targets := s.targets()
+	var err error
+	if len(targets) == 1 {
+		s.network, err = ParseRange(targets[0])
+	}
+	if err != nil {
+		fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
+		os.Exit(1)
+	}
+
+	// Open the wordlist, which is streamed to the workers
+	if s.network == nil {
+		err = s.openWordlist()
+	}
 	if err != nil {
 		fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
 		os.Exit(1)
 	}
+	if s.words != nil {
+		defer s.words.Close()
+	}
+	if s.network == nil && s.config.Learn > 0 && s.config.LearnCorpus != "" {
+		s.corpus, err = LoadWordlist(s.config.LearnCorpus)
+		if err != nil {
+			fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
+			os.Exit(1)
+		}
+	}
 
-	fmt.Printf("\033[1;34m[*] Target: %s\033[0m\n", s.config.Target)
-	fmt.Printf("\033[1;34m[*] Wordlist: %s (%d entries)\033[0m\n", s.config.Wordlist, len(s.wordlist))
+	if len(targets) == 1 {
+		fmt.Printf("\033[1;34m[*] Target: %s\033[0m\n", targets[0])
+	} else {
+		fmt.Printf("\033[1;34m[*] Targets: %d domains\033[0m\n", len(targets))
+	}
+	if s.network != nil {
+		ones, bits := s.network.Mask.Size()
+		fmt.Printf("\033[1;34m[*] Reverse DNS sweep: %d addresses\033[0m\n", 1<<(bits-ones))
+	} else if s.words != nil {
+		compressed := ""
+		if s.words.Compressed() {
+			compressed = ", gzip compressed"
+		}
+		fmt.Printf("\033[1;34m[*] Wordlist: %s (%d bytes%s)\033[0m\n", s.config.Wordlist, s.words.Size(), compressed)
+		if offset := s.words.Offset(); offset > 0 {
+			fmt.Printf("\033[1;34m[*] Resuming wordlist at byte %d (%.1f%% read)\033[0m\n", offset, s.progress.fraction()*100)
+		}
+	}
+	if s.network == nil {
+		for _, pattern := range s.config.Patterns {
+			fmt.Printf("\033[1;34m[*] Pattern: %s (%s words)\033[0m\n", pattern, formatPatternSize(pattern.Size()))
+		}
+	}
+	if len(s.candidates) > 0 {
+		fmt.Printf("\033[1;34m[*] Candidates: %d from other sources\033[0m\n", len(s.candidates))
+	}
 	fmt.Printf("\033[1;34m[*] Threads: %d\033[0m\n", s.config.Threads)
 	fmt.Println("\033[1;34m[*] Starting scan...\033[0m")
 
@@ -65,25 +213,33 @@ func (s *Scanner) Start() {
 	startTime := time.Now()
 
 	// Start result collector
+	s.collected = make(chan struct{})
 	go s.collectResults()
 
-	// Create worker pool
-	jobs := make(chan string, len(s.wordlist))
-
-	// Start workers
-	for i := 0; i < s.config.Threads; i++ {
-		go s.worker(jobs)
-	}
-
-	// Send jobs to workers
-	for _, word := range s.wordlist {
-		jobs <- word
+	if s.network != nil {
+		s.runJobs(s.sendRangeJobs)
+	} else {
+		s.wildcards = detectWildcards(targets, s.config.Threads)
+		for _, target := range targets {
+			if wildcard := s.wildcards[target]; wildcard != nil {
+				fmt.Printf("\033[1;33m[!] Wildcard DNS on %s (%s), ignoring names that only resolve there\033[0m\n", target, strings.Join(wildcardAddresses(wildcard), ", "))
+			}
+		}
+		stop := s.reportProgress(startTime)
+		s.runJobs(s.sendBruteForceJobs)
+		stop()
+		s.saveHitStats()
+		if s.config.Learn > 0 {
+			s.runJobs(s.sendLearnedJobs)
+		}
+		if s.config.PTRSweep > 0 {
+			s.runJobs(s.sendSweepJobs)
+		}
 	}
-	close(jobs)
 
-	// Wait for all workers to finish
-	s.wg.Wait()
+	// Wait for all results to be collected
 	close(s.resultChan)
+	<-s.collected
 
 	// Calculate elapsed time
 	elapsedTime := time.Since(startTime)
@@ -91,6 +247,16 @@ func (s *Scanner) Start() {
 	// Print summary
 	fmt.Println("\n\033[1;32m[+] Scan completed!\033[0m")
 	fmt.Printf("\033[1;32m[+] Found %d subdomains in %s\033[0m\n", s.countFoundSubdomains(), elapsedTime)
+	if len(targets) > 1 {
+		counts := s.countByDomain()
+		for _, target := range targets {
+			fmt.Printf("\033[1;32m[+]   %s: %d\033[0m\n", target, counts[target])
+		}
+	}
+	if s.config.IPDatabase != nil {
+		fmt.Println("\033[1;34m[*] Hosts by network owner:\033[0m")
+		fmt.Print(FormatNetworkGroups(GroupByNetwork(s.foundNetworks())))
+	}
 
 	// Save results to file if specified
 	if s.config.OutputFile != "" {
@@ -98,66 +264,354 @@ func (s *Scanner) Start() {
 	}
 }
 
-// loadWordlist loads the wordlist from file
-func (s *Scanner) loadWordlist() error {
-	file, err := os.Open(s.config.Wordlist)
-	if err != nil {
-		return fmt.Errorf("failed to open wordlist file: %v", err)
+// runJobs runs the worker pool over the jobs queued by send and waits for
+// the workers to finish
+func (s *Scanner) runJobs(send func(jobs chan<- scanJob)) {
+	jobs := make(chan scanJob, s.config.Threads)
+
+	// Start workers
+	for i := 0; i < s.config.Threads; i++ {
+		s.wg.Add(1)
+		go s.worker(jobs)
 	}
-	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		word := strings.TrimSpace(scanner.Text())
-		if word != "" && !strings.HasPrefix(word, "#") {
-			s.wordlist = append(s.wordlist, word)
+	send(jobs)
+	close(jobs)
+	s.wg.Wait()
+}
+
+// sendBruteForceJobs queues the wordlist and the candidates. Each word is
+// tried on every target before moving to the next, so the lookups of a run
+// over several domains are spread across their name servers. Wordlist names
+// that are also candidates are resolved once, with the brute force tag added
+// to the candidate.
+func (s *Scanner) sendBruteForceJobs(jobs chan<- scanJob) {
+	tagged := len(s.candidates) > 0 || s.config.PTRSweep > 0 || s.config.Learn > 0
+	var batch []scanJob
+	for s.words != nil && s.words.Scan() {
+		batch = s.wordJobs(batch[:0], s.words.Word(), tagged, true)
+		seq := s.progress.queue(len(batch))
+		for _, job := range batch {
+			job.word = seq
+			jobs <- job
+		}
+	}
+	if s.words != nil {
+		if err := s.words.Err(); err != nil {
+			fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
+		} else {
+			s.progress.end()
+		}
+	}
+	for _, pattern := range s.config.Patterns {
+		for pattern.Scan() {
+			batch = s.wordJobs(batch[:0], pattern.Word(), tagged, false)
+			for _, job := range batch {
+				jobs <- job
+			}
+		}
+	}
+	for _, candidate := range s.candidates {
+		jobs <- candidate
+	}
+}
+
+// sendLearnedJobs queues the most probable unseen names of an n-gram model
+// trained on the names found under each target and on the corpus
+func (s *Scanner) sendLearnedJobs(jobs chan<- scanJob) {
+	s.mutex.Lock()
+	names := make([]string, 0, len(s.foundNames))
+	for name := range s.foundNames {
+		names = append(names, name)
+	}
+	s.mutex.Unlock()
+	sort.Strings(names)
+
+	found := make(map[string][]string)
+	for _, name := range names {
+		if domain := s.apexOf(name); domain != "" && name != domain {
+			found[domain] = append(found[domain], strings.TrimSuffix(name, "."+domain))
+		}
+	}
+
+	for _, target := range s.targets() {
+		target = strings.ToLower(target)
+		if len(found[target]) == 0 {
+			continue
+		}
+
+		model := NewNameModel()
+		for _, word := range s.corpus {
+			model.Train(strings.TrimSuffix(strings.ToLower(word), "."+target))
+		}
+		for _, label := range found[target] {
+			model.Train(label)
+		}
+
+		labels := model.Generate(s.config.Learn, nil)
+		fmt.Printf("\033[1;34m[*] Generated %d names for %s from %d found\033[0m\n", len(labels), target, len(found[target]))
+		for _, label := range labels {
+			jobs <- scanJob{subdomain: label + "." + target, sources: []string{SourceNgram}}
+		}
+	}
+}
+
+// wordJobs appends the jobs checking a word on every target to batch. Names
+// that are also candidates are left to the candidate job, which is marked as
+// a wordlist entry when the word is one.
+func (s *Scanner) wordJobs(batch []scanJob, word string, tagged bool, entry bool) []scanJob {
+	for _, target := range s.targets() {
+		subdomain := fmt.Sprintf("%s.%s", word, target)
+		if i, ok := s.candidate[strings.ToLower(subdomain)]; ok {
+			if !containsString(s.candidates[i].sources, SourceBruteForce) {
+				s.candidates[i].sources = append(s.candidates[i].sources, SourceBruteForce)
+			}
+			s.candidates[i].entry = s.candidates[i].entry || entry
+			continue
+		}
+
+		job := scanJob{subdomain: subdomain, entry: entry}
+		if tagged {
+			job.sources = []string{SourceBruteForce}
 		}
+		batch = append(batch, job)
+	}
+	return batch
+}
+
+// openWordlist opens the wordlist for streaming and moves to the position
+// saved in the resume file. A scanner without a wordlist only resolves its
+// candidates.
+func (s *Scanner) openWordlist() error {
+	if s.config.Wordlist == "" {
+		return nil
 	}
 
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("error reading wordlist: %v", err)
+	words, err := OpenWordlist(s.config.Wordlist)
+	if err != nil {
+		return err
 	}
 
+	if s.config.ResumeFile != "" {
+		state, err := LoadResumeState(s.config.ResumeFile)
+		if err != nil {
+			words.Close()
+			return err
+		}
+		if state != nil && !state.Matches(s.config.Wordlist, s.targets()) {
+			fmt.Printf("\033[1;33m[!] Warning: Resume file %s is for another scan, starting from the beginning\033[0m\n", s.config.ResumeFile)
+		} else if state != nil {
+			if err := words.Skip(state.Offset); err != nil {
+				words.Close()
+				return err
+			}
+		}
+	}
+
+	s.words = words
+	s.progress = newWordlistProgress(words)
 	return nil
 }
 
-// worker processes subdomain checks
-func (s *Scanner) worker(jobs <-chan string) {
-	for word := range jobs {
-		s.wg.Add(1)
-		subdomain := fmt.Sprintf("%s.%s", word, s.config.Target)
-		s.checkSubdomain(subdomain)
+// reportProgress prints the wordlist progress and saves the resume file at
+// regular intervals until the returned function is called. With a resume
+// file, an interrupted scan saves its position and the results so far
+// before exiting. The resume file is removed once the wordlist is done.
+func (s *Scanner) reportProgress(startTime time.Time) func() {
+	if s.progress == nil {
+		return func() {}
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	if s.config.ResumeFile != "" {
+		signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	}
+
+	start := s.progress.fraction()
+	ticker := time.NewTicker(progressInterval)
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-ticker.C:
+				fmt.Printf("\033[1;34m[*] %s\033[0m\n", s.progress.status(start, time.Since(startTime)))
+				s.saveResume()
+			case <-interrupt:
+				s.saveResume()
+				fmt.Printf("\n\033[1;33m[!] Interrupted, continue with --resume %s\033[0m\n", s.config.ResumeFile)
+				if s.config.OutputFile != "" {
+					s.saveResults()
+				}
+				os.Exit(130)
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(stop)
+		<-stopped
+		signal.Stop(interrupt)
+		if s.config.ResumeFile != "" {
+			os.Remove(s.config.ResumeFile)
+		}
+	}
+}
+
+// saveResume records the wordlist position in the resume file
+func (s *Scanner) saveResume() {
+	if s.config.ResumeFile == "" {
+		return
+	}
+
+	state := &ResumeState{
+		Wordlist: s.config.Wordlist,
+		Targets:  s.targets(),
+		Offset:   s.progress.resumeOffset(),
+	}
+	if err := state.Save(s.config.ResumeFile); err != nil {
+		fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
 	}
 }
 
-// checkSubdomain checks if a subdomain exists
-func (s *Scanner) checkSubdomain(subdomain string) {
+// worker processes subdomain checks
+func (s *Scanner) worker(jobs <-chan scanJob) {
 	defer s.wg.Done()
 
+	for job := range jobs {
+		switch {
+		case job.address != "" && !InScope(job.address):
+			logOutOfScope(job.address)
+		case job.address != "":
+			s.checkAddress(job.address)
+		case !InScope(job.subdomain):
+			logOutOfScope(job.subdomain)
+		default:
+			if s.checkSubdomain(job.subdomain, job.sources) && job.entry {
+				s.recordHit(job.subdomain)
+			}
+		}
+		if job.word > 0 {
+			s.progress.done(job.word)
+		}
+	}
+}
+
+// checkSubdomain checks if a subdomain exists and reports whether it was found
+func (s *Scanner) checkSubdomain(subdomain string, sources []string) bool {
 	result := ScanResult{
 		Subdomain: subdomain,
+		Domain:    s.apexOf(strings.ToLower(subdomain)),
 		Found:     false,
+		Sources:   sources,
 	}
 
-	ips, err := net.LookupIP(subdomain)
+	ips, err := utils.Resolver().LookupIP(context.Background(), "ip", subdomain)
 	if err == nil && len(ips) > 0 {
+		addresses := make([]string, len(ips))
+		for i, ip := range ips {
+			addresses[i] = ip.String()
+		}
+
+		// Names answered by a wildcard record are reported as not found
+		if isWildcardAnswer(s.wildcards[result.Domain], addresses) {
+			s.resultChan <- result
+			return false
+		}
+
+		// Names pointing outside the scope are dropped so later stages never contact them
+		if !InScope(subdomain, addresses...) {
+			logOutOfScope(fmt.Sprintf("%s -> %s", subdomain, strings.Join(addresses, ", ")))
+			return false
+		}
+
 		result.Found = true
 		result.IP = ips[0].String()
+		result.Network = s.config.IPDatabase.Lookup(result.IP)
+		result.Attribution = AttributeHost(subdomain, result.IP)
+
+		s.mutex.Lock()
+		s.foundIPs[result.IP] = true
+		s.foundNames[strings.ToLower(subdomain)] = true
+		s.mutex.Unlock()
 	}
 
 	s.resultChan <- result
+	return result.Found
+}
+
+// recordHit counts a wordlist entry that resolved on one of the targets
+func (s *Scanner) recordHit(subdomain string) {
+	subdomain = strings.ToLower(subdomain)
+	label := strings.TrimSuffix(subdomain, "."+s.apexOf(subdomain))
+
+	s.mutex.Lock()
+	s.hits[label]++
+	s.mutex.Unlock()
+}
+
+// saveHitStats adds the wordlist entries that resolved in this scan to the
+// hit statistics file. Only a pass over the whole list is recorded, since
+// the scans counted are what the hit rates of every entry are based on;
+// interrupted, resumed and failed passes are left out.
+func (s *Scanner) saveHitStats() {
+	if s.config.HitStats == "" || s.words == nil {
+		return
+	}
+	if !s.progress.complete() {
+		fmt.Println("\033[1;33m[!] Warning: Wordlist not read from start to end, hit statistics not updated\033[0m")
+		return
+	}
+
+	stats, err := LoadHitStats(s.config.HitStats)
+	if err != nil {
+		fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
+		return
+	}
+
+	s.mutex.Lock()
+	stats.Record(s.config.Wordlist, len(s.targets()), s.hits)
+	s.mutex.Unlock()
+	if err := stats.Save(); err != nil {
+		fmt.Printf("\033[1;31m[!] Error: %v\033[0m\n", err)
+	}
 }
 
 // collectResults collects and processes scan results
 func (s *Scanner) collectResults() {
-	for result := range s.resultChan {
-		s.mutex.Lock()
-		s.results = append(s.results, result)
-		s.mutex.Unlock()
+	defer close(s.collected)
+	if s.stream != nil {
+		defer close(s.stream)
+	}
 
+	for result := range s.resultChan {
+		// Misses are streamed and printed but not kept, a large wordlist
+		// would otherwise hold every name it tried
 		if result.Found {
+			s.mutex.Lock()
+			s.results = append(s.results, result)
+			s.mutex.Unlock()
+		}
+
+		if s.stream != nil {
+			s.stream <- result
+		} else if result.Found {
 			green := color.New(color.FgGreen).SprintFunc()
-			fmt.Printf("%s %s -> %s\n", green("[+]"), result.Subdomain, result.IP)
+			line := fmt.Sprintf("%s %s -> %s", green("[+]"), result.Subdomain, result.IP)
+			if result.Network != nil {
+				line += " (" + result.Network.Owner() + ")"
+			}
+			if result.Attribution != nil {
+				line += " via " + result.Attribution.String()
+			}
+			if len(result.Sources) > 0 {
+				line += " [" + strings.Join(result.Sources, ",") + "]"
+			}
+			fmt.Println(line)
 		} else if s.config.Verbose {
 			red := color.New(color.FgRed).SprintFunc()
 			fmt.Printf("%s %s\n", red("[-]"), result.Subdomain)
@@ -167,13 +621,25 @@ func (s *Scanner) collectResults() {
 
 // countFoundSubdomains counts the number of found subdomains
 func (s *Scanner) countFoundSubdomains() int {
-	count := 0
+	return len(s.results)
+}
+
+// countByDomain counts the found subdomains of each target domain
+func (s *Scanner) countByDomain() map[string]int {
+	counts := make(map[string]int)
 	for _, result := range s.results {
-		if result.Found {
-			count++
-		}
+		counts[result.Domain]++
 	}
-	return count
+	return counts
+}
+
+// foundNetworks returns the network of every subdomain found
+func (s *Scanner) foundNetworks() map[string]*IPInfo {
+	networks := make(map[string]*IPInfo)
+	for _, result := range s.results {
+		networks[result.Subdomain] = result.Network
+	}
+	return networks
 }
 
 // saveResults saves the scan results to a file
@@ -185,17 +651,60 @@ func (s *Scanner) saveResults() {
 	}
 	defer file.Close()
 
-	writer := bufio.NewWriter(file)
-	writer.WriteString(fmt.Sprintf("# Sub Domain Scan Results for %s\n", s.config.Target))
-	writer.WriteString(fmt.Sprintf("# Generated by Sub Tool - By SayerLinux (SaudiSayer@gmail.com)\n"))
-	writer.WriteString(fmt.Sprintf("# Date: %s\n\n", time.Now().Format(time.RFC1123)))
+	s.mutex.Lock()
+	results := append([]ScanResult(nil), s.results...)
+	s.mutex.Unlock()
 
-	for _, result := range s.results {
+	if err := WriteResults(file, strings.Join(s.targets(), ", "), s.config.OutputFormat, results); err != nil {
+		fmt.Printf("\033[1;31m[!] Error: Failed to write output file: %v\033[0m\n", err)
+		return
+	}
+
+	fmt.Printf("\033[1;32m[+] Results saved to %s\033[0m\n", s.config.OutputFile)
+}
+
+// WriteResults writes the found subdomains of a scan in the given output
+// format, json or csv, grouped by the apex domain they were found under
+func WriteResults(w io.Writer, target string, format string, results []ScanResult) error {
+	var found []ScanResult
+	for _, result := range results {
 		if result.Found {
-			writer.WriteString(fmt.Sprintf("%s,%s\n", result.Subdomain, result.IP))
+			found = append(found, result)
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].Domain < found[j].Domain
+	})
+
+	writer := bufio.NewWriter(w)
+	if format == "json" {
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		if found == nil {
+			found = []ScanResult{}
+		}
+		if err := encoder.Encode(found); err != nil {
+			return err
+		}
+	} else {
+		writer.WriteString(fmt.Sprintf("# Sub Domain Scan Results for %s\n", target))
+		writer.WriteString(fmt.Sprintf("# Generated by Sub Tool - By SayerLinux (SaudiSayer@gmail.com)\n"))
+		writer.WriteString(fmt.Sprintf("# Date: %s\n\n", time.Now().Format(time.RFC1123)))
+
+		for i, result := range found {
+			if result.Domain != "" && (i == 0 || result.Domain != found[i-1].Domain) {
+				if i > 0 {
+					writer.WriteString("\n")
+				}
+				writer.WriteString(fmt.Sprintf("# Domain: %s\n", result.Domain))
+			}
+			if len(result.Sources) > 0 {
+				writer.WriteString(fmt.Sprintf("%s,%s,%s\n", result.Subdomain, result.IP, strings.Join(result.Sources, "|")))
+			} else {
+				writer.WriteString(fmt.Sprintf("%s,%s\n", result.Subdomain, result.IP))
+			}
 		}
 	}
 
-	writer.Flush()
-	fmt.Printf("\033[1;32m[+] Results saved to %s\033[0m\n", s.config.OutputFile)
+	return writer.Flush()
 }
\ No newline at end of file
@@ -0,0 +1,236 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/SayerLinux/sub/pkg/utils"
+)
+
+// Scope lists the domains and networks that may be contacted. Names must
+// match an included domain pattern and addresses an included network, unless
+// no pattern or no network is included, and neither may match an exclusion.
+type Scope struct {
+	includeDomains []string
+	excludeDomains []string
+	includeNets    []*net.IPNet
+	excludeNets    []*net.IPNet
+}
+
+var (
+	activeScope *Scope
+	scopeLock   sync.RWMutex
+)
+
+// LoadScope loads a scope file and makes every active check (brute force,
+// PTR lookups, port dials, HTTP requests and virtual host probes) skip the
+// names and addresses outside of it. Each line of the file is a domain
+// pattern, an IP or a CIDR; lines starting with ! are exclusions and # starts
+// a comment. A plain domain covers its subdomains, patterns may use * and ?.
+//
+//	example.com
+//	*.example.org
+//	203.0.113.0/24
+//	!admin.example.com
+//	!203.0.113.128/25
+func LoadScope(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open scope file: %v", err)
+	}
+	defer file.Close()
+
+	scope, err := parseScope(file)
+	if err != nil {
+		return fmt.Errorf("invalid scope file %s: %v", path, err)
+	}
+
+	scopeLock.Lock()
+	activeScope = scope
+	scopeLock.Unlock()
+	return nil
+}
+
+// parseScope reads the rules of a scope file
+func parseScope(reader io.Reader) (*Scope, error) {
+	scope := &Scope{}
+
+	scanner := bufio.NewScanner(reader)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		exclude := strings.HasPrefix(line, "!")
+		line = strings.TrimSpace(strings.TrimPrefix(line, "!"))
+
+		if network := parseScopeNetwork(line); network != nil {
+			if exclude {
+				scope.excludeNets = append(scope.excludeNets, network)
+			} else {
+				scope.includeNets = append(scope.includeNets, network)
+			}
+			continue
+		}
+
+		pattern := strings.ToLower(strings.TrimSuffix(line, "."))
+		if strings.Contains(pattern, "/") {
+			return nil, fmt.Errorf("line %d: invalid network %q", lineNumber, line)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("line %d: invalid pattern %q", lineNumber, line)
+		}
+		if exclude {
+			scope.excludeDomains = append(scope.excludeDomains, pattern)
+		} else {
+			scope.includeDomains = append(scope.includeDomains, pattern)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(scope.includeDomains) == 0 && len(scope.includeNets) == 0 {
+		return nil, fmt.Errorf("no domains or networks are included")
+	}
+	return scope, nil
+}
+
+// parseScopeNetwork parses a CIDR or a single address, returning nil for
+// anything else
+func parseScopeNetwork(value string) *net.IPNet {
+	if _, network, err := net.ParseCIDR(value); err == nil {
+		return network
+	}
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}
+}
+
+// AllowsName reports whether a host name is in scope
+func (s *Scope) AllowsName(name string) bool {
+	if s == nil {
+		return true
+	}
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+
+	for _, pattern := range s.excludeDomains {
+		if matchDomain(pattern, name) {
+			return false
+		}
+	}
+	if len(s.includeDomains) == 0 {
+		return true
+	}
+	for _, pattern := range s.includeDomains {
+		if matchDomain(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsIP reports whether an address is in scope
+func (s *Scope) AllowsIP(address string) bool {
+	if s == nil {
+		return true
+	}
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range s.excludeNets {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	if len(s.includeNets) == 0 {
+		return true
+	}
+	for _, network := range s.includeNets {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows reports whether a host, given as a name or an address, is in scope
+func (s *Scope) Allows(host string) bool {
+	if net.ParseIP(host) != nil {
+		return s.AllowsIP(host)
+	}
+	return s.AllowsName(host)
+}
+
+// matchDomain matches a name against a scope pattern. Plain domains match
+// themselves and their subdomains.
+func matchDomain(pattern string, name string) bool {
+	if strings.ContainsAny(pattern, "*?[") {
+		matched, _ := path.Match(pattern, name)
+		return matched
+	}
+	return isUnderDomain(name, pattern)
+}
+
+// InScope reports whether a host and the addresses it resolves to are all in
+// the loaded scope. Everything is in scope when no scope file is loaded.
+func InScope(host string, ips ...string) bool {
+	scopeLock.RLock()
+	scope := activeScope
+	scopeLock.RUnlock()
+
+	if !scope.Allows(host) {
+		return false
+	}
+	for _, ip := range ips {
+		if !scope.AllowsIP(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// HostInScope resolves a host name when the loaded scope restricts addresses
+// and reports whether the name and every address are in scope. Names that do
+// not resolve are only checked by name.
+func HostInScope(host string) bool {
+	scopeLock.RLock()
+	scope := activeScope
+	scopeLock.RUnlock()
+
+	if !scope.Allows(host) {
+		return false
+	}
+	if scope == nil || len(scope.includeNets) == 0 && len(scope.excludeNets) == 0 || net.ParseIP(host) != nil {
+		return true
+	}
+
+	ips, _ := utils.Resolver().LookupHost(context.Background(), host)
+	return InScope(host, ips...)
+}
+
+// logOutOfScope reports an item skipped because it is out of scope
+func logOutOfScope(item string) {
+	fmt.Printf("\033[1;33m[!] Skipping out of scope: %s\033[0m\n", item)
+}
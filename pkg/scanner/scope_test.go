@@ -0,0 +1,57 @@
+package scanner
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScope(t *testing.T) {
+	scope, err := parseScope(strings.NewReader(`# Rules of engagement
+example.com
+*.example.org      # subdomains only
+203.0.113.0/24
+198.51.100.7
+!admin.example.com
+!203.0.113.128/25
+`))
+	if err != nil {
+		t.Fatalf("parseScope failed: %v", err)
+	}
+
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"example.com", true},
+		{"www.example.com", true},
+		{"WWW.Example.com.", true},
+		{"admin.example.com", false},
+		{"dev.admin.example.com", false},
+		{"example.org", false},
+		{"api.example.org", true},
+		{"notexample.com", false},
+		{"203.0.113.10", true},
+		{"203.0.113.200", false},
+		{"198.51.100.7", true},
+		{"198.51.100.8", false},
+	}
+	for _, test := range tests {
+		if got := scope.Allows(test.host); got != test.want {
+			t.Errorf("Allows(%s) = %v, want %v", test.host, got, test.want)
+		}
+	}
+
+	// A loaded scope checks the addresses a name resolves to as well
+	activeScope = scope
+	defer func() { activeScope = nil }()
+	if !InScope("www.example.com", "203.0.113.10") || InScope("www.example.com", "192.0.2.1") {
+		t.Error("InScope ignored the resolved addresses")
+	}
+
+	if _, err := parseScope(strings.NewReader("!admin.example.com\n")); err == nil {
+		t.Error("scope with only exclusions accepted")
+	}
+	if _, err := parseScope(strings.NewReader("10.0.0.0/33\n")); err == nil {
+		t.Error("invalid network accepted")
+	}
+}
@@ -0,0 +1,225 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// maxSecretLineLength is the longest line inspected by the secret scanner
+const maxSecretLineLength = 1 << 20
+
+// SecretRule describes a kind of secret. Group selects the capture group
+// holding the secret value (0 for the whole match), Files restricts the rule to
+// file names matching one of the glob patterns and MinEntropy discards values
+// that look like placeholders rather than real secrets.
+type SecretRule struct {
+	ID          string
+	Description string
+	Pattern     *regexp.Regexp
+	Group       int
+	Files       []string
+	MinEntropy  float64
+}
+
+// SecretFinding represents a secret found in a downloaded file
+type SecretFinding struct {
+	File   string
+	Line   int
+	RuleID string
+	Secret string
+}
+
+// SecretRules are the rules applied by ScanSecrets
+var SecretRules = []SecretRule{
+	{
+		ID:          "aws-access-key-id",
+		Description: "AWS access key ID",
+		Pattern:     regexp.MustCompile(`\b((?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA|ANVA|AIPA)[A-Z0-9]{16})\b`),
+		Group:       1,
+	},
+	{
+		ID:          "aws-secret-access-key",
+		Description: "AWS secret access key",
+		Pattern:     regexp.MustCompile(`(?i)aws.{0,20}?(?:secret|private).{0,20}?['"]?\s*[:=]\s*['"]?([A-Za-z0-9/+=]{40})\b`),
+		Group:       1,
+		MinEntropy:  4.0,
+	},
+	{
+		ID:          "private-key",
+		Description: "Private key",
+		Pattern:     regexp.MustCompile(`-----BEGIN (?:RSA |DSA |EC |OPENSSH |PGP |ENCRYPTED )?PRIVATE KEY(?: BLOCK)?-----`),
+	},
+	{
+		ID:          "database-url",
+		Description: "Database connection URL with credentials",
+		Pattern:     regexp.MustCompile(`(?i)\b(?:mysql|mariadb|postgres|postgresql|mongodb(?:\+srv)?|redis|rediss|amqp|amqps|mssql|sqlserver|jdbc:[a-z]+)://[^:@\s/'"]+:([^@\s'"]+)@[^\s'"]+`),
+		Group:       1,
+	},
+	{
+		ID:          "jwt",
+		Description: "JSON Web Token",
+		Pattern:     regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`),
+	},
+	{
+		ID:          "env-password",
+		Description: "Password or secret in an environment file",
+		Pattern:     regexp.MustCompile(`(?i)^\s*(?:export\s+)?[A-Z0-9_]*(?:PASSWORD|PASSWD|PASS|PWD|SECRET|TOKEN|API_?KEY|PRIVATE_KEY)[A-Z0-9_]*\s*=\s*['"]?([^'"\s#]+)`),
+		Group:       1,
+		Files:       []string{".env", ".env.*", "*.env"},
+	},
+	{
+		ID:          "php-config-password",
+		Description: "Password in a PHP configuration file",
+		Pattern:     regexp.MustCompile(`(?i)(?:define\s*\(\s*['"][A-Z0-9_]*(?:PASSWORD|PASS|SECRET|KEY|SALT)[A-Z0-9_]*['"]\s*,\s*|\$[A-Za-z0-9_]*(?:password|passwd|pass|pwd|secret)[A-Za-z0-9_]*(?:\[['"][^'"]*['"]\])?\s*=\s*|\[['"][A-Za-z0-9_]*(?:password|passwd|pass|pwd|secret)[A-Za-z0-9_]*['"]\]\s*=\s*|['"](?:password|passwd|pass|secret)['"]\s*=>\s*)['"]([^'"]+)['"]`),
+		Group:       1,
+		Files:       []string{"*.php", "*.php.*", "*.inc"},
+	},
+	{
+		ID:          "generic-secret",
+		Description: "High entropy value assigned to a secret-like key",
+		Pattern:     regexp.MustCompile(`(?i)(?:key|secret|token|passw(?:or)?d|credential)[A-Za-z0-9_-]*['"]?\s*[:=]\s*['"]([A-Za-z0-9/+_=.-]{20,})['"]`),
+		Group:       1,
+		MinEntropy:  4.0,
+	},
+}
+
+// ScanSecrets runs the secret rules over a file, or over every file below a
+// directory, and returns the findings with their values masked unless reveal is set.
+// Version control internals are skipped as their content is compressed.
+func ScanSecrets(path string, reveal bool) ([]SecretFinding, error) {
+	var findings []SecretFinding
+
+	err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		fileFindings, err := scanFileSecrets(file, reveal)
+		if err != nil {
+			return err
+		}
+		findings = append(findings, fileFindings...)
+		return nil
+	})
+
+	return findings, err
+}
+
+// scanFileSecrets applies the secret rules to every line of a text file
+func scanFileSecrets(path string, reveal bool) ([]SecretFinding, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	// Binary files are not inspected
+	if bytes.IndexByte(data[:min(len(data), 8000)], 0) != -1 {
+		return nil, nil
+	}
+
+	var rules []SecretRule
+	for _, rule := range SecretRules {
+		if rule.appliesTo(path) {
+			rules = append(rules, rule)
+		}
+	}
+
+	var findings []SecretFinding
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSecretLineLength)
+
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+
+		// Rules are ordered from specific to generic, report each value once
+		reported := make(map[string]bool)
+		for _, rule := range rules {
+			for _, match := range rule.Pattern.FindAllStringSubmatch(line, -1) {
+				secret := match[rule.Group]
+				if reported[secret] || rule.MinEntropy > 0 && shannonEntropy(secret) < rule.MinEntropy {
+					continue
+				}
+				reported[secret] = true
+				if !reveal {
+					secret = MaskSecret(secret)
+				}
+				findings = append(findings, SecretFinding{
+					File:   path,
+					Line:   lineNumber,
+					RuleID: rule.ID,
+					Secret: secret,
+				})
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return findings, fmt.Errorf("failed to scan %s: %v", path, err)
+	}
+
+	return findings, nil
+}
+
+// appliesTo reports whether a rule should run on a file
+func (rule SecretRule) appliesTo(path string) bool {
+	if len(rule.Files) == 0 {
+		return true
+	}
+
+	name := filepath.Base(path)
+	for _, pattern := range rule.Files {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// MaskSecret hides a secret except for at most a quarter of its characters,
+// split between its start and end, so a finding can be recognised without
+// being usable. Secrets of 8 characters or fewer are hidden entirely.
+func MaskSecret(secret string) string {
+	runes := []rune(secret)
+	if len(runes) <= 8 {
+		return strings.Repeat("*", len(runes))
+	}
+
+	shown := len(runes) / 4
+	lead, trail := (shown+1)/2, shown/2
+	return string(runes[:lead]) + strings.Repeat("*", len(runes)-shown) + string(runes[len(runes)-trail:])
+}
+
+// shannonEntropy returns the Shannon entropy of a string in bits per character
+func shannonEntropy(value string) float64 {
+	if value == "" {
+		return 0
+	}
+
+	counts := make(map[rune]int)
+	for _, r := range value {
+		counts[r]++
+	}
+
+	var entropy float64
+	length := float64(len([]rune(value)))
+	for _, count := range counts {
+		p := float64(count) / length
+		entropy -= p * math.Log2(p)
+	}
+
+	return entropy
+}
@@ -0,0 +1,240 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SayerLinux/sub/pkg/utils"
+)
+
+// maxSourceResponseSize is the maximum size of a passive source response
+const maxSourceResponseSize = 50 << 20
+
+// Source is a passive provider of candidate subdomains
+type Source interface {
+	// Name returns the name results are tagged with
+	Name() string
+
+	// Enumerate returns the names the provider knows under domain
+	Enumerate(ctx context.Context, domain string) ([]string, error)
+}
+
+// SourceConfig holds the settings of a single passive source
+type SourceConfig struct {
+	BaseURL  string `json:"base_url,omitempty"`
+	APIKey   string `json:"api_key,omitempty"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
+
+// SourcesConfig holds the settings of all passive sources keyed by source name
+type SourcesConfig struct {
+	Timeout int                     `json:"timeout,omitempty"`
+	Sources map[string]SourceConfig `json:"sources"`
+}
+
+// PassiveResult is a name found by one or more passive sources
+type PassiveResult struct {
+	Subdomain string
+	Sources   []string
+}
+
+// DefaultSourcesConfigPath returns the default location of the sources
+// configuration file, inside the user configuration directory
+func DefaultSourcesConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "sub", "sources.json")
+}
+
+// LoadSourcesConfig loads the passive sources configuration from a JSON file.
+// A missing file yields an empty configuration so the built-in defaults apply.
+func LoadSourcesConfig(path string) (SourcesConfig, error) {
+	config := SourcesConfig{Sources: map[string]SourceConfig{}}
+	if path == "" {
+		return config, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return config, fmt.Errorf("failed to read sources config: %v", err)
+	}
+
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("invalid sources config %s: %v", path, err)
+	}
+	if config.Sources == nil {
+		config.Sources = map[string]SourceConfig{}
+	}
+
+	return config, nil
+}
+
+// NewSources creates the named passive sources, or every known source if names
+// is empty. Sources disabled in the configuration and sources that need an API
+// key without one configured are skipped.
+func NewSources(names []string, config SourcesConfig) ([]Source, error) {
+	if len(names) == 0 {
+		for name := range sourceFactories {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	timeout := 30 * time.Second
+	if config.Timeout > 0 {
+		timeout = time.Duration(config.Timeout) * time.Second
+	}
+	client := &http.Client{Timeout: timeout, Transport: utils.NewTransport(false)}
+
+	var sources []Source
+	for _, name := range names {
+		factory, ok := sourceFactories[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown passive source %q", name)
+		}
+
+		sourceConfig := config.Sources[name]
+		if sourceConfig.Disabled {
+			continue
+		}
+
+		source := factory(client, sourceConfig)
+		if source == nil {
+			continue
+		}
+		sources = append(sources, source)
+	}
+
+	return sources, nil
+}
+
+// SourceNames returns the names of all known passive sources
+func SourceNames() []string {
+	var names []string
+	for name := range sourceFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// EnumerateSources queries all sources concurrently and merges their results.
+// Only names under domain are kept, lowercased and deduplicated, each tagged
+// with every source that reported it. Source failures are returned alongside
+// the results of the sources that succeeded.
+func EnumerateSources(ctx context.Context, domain string, sources []Source) ([]PassiveResult, []error) {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+
+	var (
+		mutex  sync.Mutex
+		wg     sync.WaitGroup
+		found  = make(map[string][]string)
+		errors []error
+	)
+
+	for _, source := range sources {
+		wg.Add(1)
+		go func(source Source) {
+			defer wg.Done()
+
+			names, err := source.Enumerate(ctx, domain)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			if err != nil {
+				errors = append(errors, fmt.Errorf("%s: %v", source.Name(), err))
+			}
+			for _, name := range names {
+				name = normaliseName(name)
+				if !isUnderDomain(name, domain) {
+					continue
+				}
+				if !containsString(found[name], source.Name()) {
+					found[name] = append(found[name], source.Name())
+				}
+			}
+		}(source)
+	}
+	wg.Wait()
+
+	results := make([]PassiveResult, 0, len(found))
+	for name, tags := range found {
+		sort.Strings(tags)
+		results = append(results, PassiveResult{Subdomain: name, Sources: tags})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Subdomain < results[j].Subdomain
+	})
+
+	return results, errors
+}
+
+// normaliseName lowercases a name and strips wildcard labels and trailing dots
+func normaliseName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.TrimSuffix(name, ".")
+	for strings.HasPrefix(name, "*.") {
+		name = name[2:]
+	}
+	return name
+}
+
+// isUnderDomain reports whether name is domain or one of its subdomains
+func isUnderDomain(name string, domain string) bool {
+	return name == domain || strings.HasSuffix(name, "."+domain)
+}
+
+// containsString reports whether a slice contains a value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchSource performs a GET request for a passive source and returns the body
+func fetchSource(ctx context.Context, client *http.Client, url string, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json, text/plain, */*")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSourceResponseSize))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d from %s", resp.StatusCode, req.URL.Host)
+	}
+
+	return body, nil
+}
@@ -0,0 +1,256 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// newSourceServer starts a stand-in for a provider API that serves body for
+// requests to path and records the last request it received
+func newSourceServer(t *testing.T, path string, body string) (*httptest.Server, **http.Request) {
+	t.Helper()
+
+	var last *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		last = r
+		if r.URL.Path != path {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &last
+}
+
+// newTestSource creates a named source pointed at a stand-in server
+func newTestSource(t *testing.T, name string, config SourceConfig) Source {
+	t.Helper()
+
+	sources, err := NewSources([]string{name}, SourcesConfig{Sources: map[string]SourceConfig{name: config}})
+	if err != nil {
+		t.Fatalf("NewSources(%s) failed: %v", name, err)
+	}
+	if len(sources) != 1 {
+		t.Fatalf("NewSources(%s) returned %d sources", name, len(sources))
+	}
+	return sources[0]
+}
+
+func sortedNames(names []string) []string {
+	sort.Strings(names)
+	return names
+}
+
+func TestCrtShSource(t *testing.T) {
+	server, last := newSourceServer(t, "/", `[
+		{"common_name": "www.example.com", "name_value": "www.example.com\nmail.example.com"},
+		{"common_name": "*.dev.example.com", "name_value": "*.dev.example.com"}
+	]`)
+
+	source := newTestSource(t, "crtsh", SourceConfig{BaseURL: server.URL})
+	names, err := source.Enumerate(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("Enumerate failed: %v", err)
+	}
+
+	if got := (*last).URL.Query().Get("q"); got != "%.example.com" {
+		t.Errorf("query = %q, want %%.example.com", got)
+	}
+
+	want := []string{"*.dev.example.com", "*.dev.example.com", "mail.example.com", "www.example.com", "www.example.com"}
+	if got := sortedNames(names); !reflect.DeepEqual(got, want) {
+		t.Errorf("names = %v, want %v", got, want)
+	}
+}
+
+func TestHackerTargetSource(t *testing.T) {
+	server, last := newSourceServer(t, "/hostsearch/", "www.example.com,192.0.2.1\napi.example.com,192.0.2.2\n")
+
+	source := newTestSource(t, "hackertarget", SourceConfig{BaseURL: server.URL, APIKey: "secret"})
+	names, err := source.Enumerate(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("Enumerate failed: %v", err)
+	}
+
+	if got := (*last).URL.Query().Get("apikey"); got != "secret" {
+		t.Errorf("apikey = %q, want secret", got)
+	}
+
+	want := []string{"api.example.com", "www.example.com"}
+	if got := sortedNames(names); !reflect.DeepEqual(got, want) {
+		t.Errorf("names = %v, want %v", got, want)
+	}
+}
+
+func TestHackerTargetSourceError(t *testing.T) {
+	server, _ := newSourceServer(t, "/hostsearch/", "API count exceeded - Increase Quota with Membership")
+
+	source := newTestSource(t, "hackertarget", SourceConfig{BaseURL: server.URL})
+	if _, err := source.Enumerate(context.Background(), "example.com"); err == nil {
+		t.Error("Enumerate succeeded on a quota error")
+	}
+}
+
+func TestAlienVaultSource(t *testing.T) {
+	server, last := newSourceServer(t, "/api/v1/indicators/domain/example.com/passive_dns", `{
+		"passive_dns": [{"hostname": "vpn.example.com"}, {"hostname": "WWW.example.com"}]
+	}`)
+
+	source := newTestSource(t, "alienvault", SourceConfig{BaseURL: server.URL, APIKey: "otx-key"})
+	names, err := source.Enumerate(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("Enumerate failed: %v", err)
+	}
+
+	if got := (*last).Header.Get("X-OTX-API-KEY"); got != "otx-key" {
+		t.Errorf("X-OTX-API-KEY = %q, want otx-key", got)
+	}
+
+	want := []string{"WWW.example.com", "vpn.example.com"}
+	if got := sortedNames(names); !reflect.DeepEqual(got, want) {
+		t.Errorf("names = %v, want %v", got, want)
+	}
+}
+
+func TestSecurityTrailsSource(t *testing.T) {
+	server, last := newSourceServer(t, "/v1/domain/example.com/subdomains", `{"subdomains": ["www", "dev.api"]}`)
+
+	source := newTestSource(t, "securitytrails", SourceConfig{BaseURL: server.URL, APIKey: "st-key"})
+	names, err := source.Enumerate(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("Enumerate failed: %v", err)
+	}
+
+	if got := (*last).Header.Get("APIKEY"); got != "st-key" {
+		t.Errorf("APIKEY = %q, want st-key", got)
+	}
+
+	want := []string{"dev.api.example.com", "www.example.com"}
+	if got := sortedNames(names); !reflect.DeepEqual(got, want) {
+		t.Errorf("names = %v, want %v", got, want)
+	}
+}
+
+func TestSecurityTrailsSourceNeedsKey(t *testing.T) {
+	sources, err := NewSources([]string{"securitytrails"}, SourcesConfig{})
+	if err != nil {
+		t.Fatalf("NewSources failed: %v", err)
+	}
+	if len(sources) != 0 {
+		t.Errorf("securitytrails created without an API key")
+	}
+}
+
+func TestWaybackSource(t *testing.T) {
+	server, last := newSourceServer(t, "/cdx/search/cdx", "http://www.example.com/index.html\nhttps://shop.example.com:8443/cart\nwww.example.com/about\n")
+
+	source := newTestSource(t, "wayback", SourceConfig{BaseURL: server.URL})
+	names, err := source.Enumerate(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("Enumerate failed: %v", err)
+	}
+
+	if got := (*last).URL.Query().Get("url"); got != "*.example.com/*" {
+		t.Errorf("url = %q, want *.example.com/*", got)
+	}
+
+	want := []string{"shop.example.com", "www.example.com"}
+	if got := sortedNames(names); !reflect.DeepEqual(got, want) {
+		t.Errorf("names = %v, want %v", got, want)
+	}
+}
+
+func TestSourceHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "rate limited", http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	source := newTestSource(t, "crtsh", SourceConfig{BaseURL: server.URL})
+	if _, err := source.Enumerate(context.Background(), "example.com"); err == nil {
+		t.Error("Enumerate succeeded on a 429 response")
+	}
+}
+
+// staticSource is a source returning fixed names
+type staticSource struct {
+	name  string
+	names []string
+	err   error
+}
+
+func (s staticSource) Name() string { return s.name }
+
+func (s staticSource) Enumerate(ctx context.Context, domain string) ([]string, error) {
+	return s.names, s.err
+}
+
+func TestEnumerateSources(t *testing.T) {
+	sources := []Source{
+		staticSource{name: "one", names: []string{"WWW.example.com.", "*.api.example.com", "example.org", "notexample.com"}},
+		staticSource{name: "two", names: []string{"www.example.com", "mail.example.com", "www.example.com"}},
+		staticSource{name: "broken", err: errors.New("unavailable")},
+	}
+
+	results, errs := EnumerateSources(context.Background(), "Example.com", sources)
+	if len(errs) != 1 {
+		t.Errorf("got %d errors, want 1", len(errs))
+	}
+
+	want := []PassiveResult{
+		{Subdomain: "api.example.com", Sources: []string{"one"}},
+		{Subdomain: "mail.example.com", Sources: []string{"two"}},
+		{Subdomain: "www.example.com", Sources: []string{"one", "two"}},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("results = %v, want %v", results, want)
+	}
+}
+
+func TestLoadSourcesConfig(t *testing.T) {
+	config, err := LoadSourcesConfig(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("missing config failed: %v", err)
+	}
+	if len(config.Sources) != 0 {
+		t.Errorf("missing config has sources: %v", config.Sources)
+	}
+
+	path := filepath.Join(t.TempDir(), "sources.json")
+	data := `{"timeout": 5, "sources": {"securitytrails": {"api_key": "k"}, "wayback": {"disabled": true}}}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err = LoadSourcesConfig(path)
+	if err != nil {
+		t.Fatalf("LoadSourcesConfig failed: %v", err)
+	}
+
+	sources, err := NewSources(nil, config)
+	if err != nil {
+		t.Fatalf("NewSources failed: %v", err)
+	}
+
+	var names []string
+	for _, source := range sources {
+		names = append(names, source.Name())
+	}
+	want := []string{"alienvault", "crtsh", "hackertarget", "securitytrails"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("sources = %v, want %v", names, want)
+	}
+
+	if _, err := NewSources([]string{"unknown"}, config); err == nil {
+		t.Error("NewSources accepted an unknown source")
+	}
+}
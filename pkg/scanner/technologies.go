@@ -0,0 +1,154 @@
+package scanner
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+//go:embed data/technologies.json
+var defaultTechSignatures []byte
+
+// TechSignature describes how to recognise a technology from an HTTP response.
+// A technology is detected when any of its header, cookie or body patterns match.
+type TechSignature struct {
+	Name    string            `json:"name"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Cookies []string          `json:"cookies,omitempty"`
+	Body    []string          `json:"body,omitempty"`
+
+	headers map[string]*regexp.Regexp
+	body    []*regexp.Regexp
+}
+
+var (
+	techSignatures     []TechSignature
+	techSignaturesOnce sync.Once
+	techSignaturesErr  error
+	techSignaturesLock sync.RWMutex
+)
+
+// LoadTechSignatures replaces the built-in technology signatures with the ones
+// from a JSON file using the same format as data/technologies.json
+func LoadTechSignatures(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read signature file: %v", err)
+	}
+
+	signatures, err := parseTechSignatures(data)
+	if err != nil {
+		return fmt.Errorf("invalid signature file %s: %v", path, err)
+	}
+
+	// Make sure the built-in signatures are not loaded over the custom ones later
+	techSignaturesOnce.Do(func() {})
+
+	techSignaturesLock.Lock()
+	techSignatures = signatures
+	techSignaturesErr = nil
+	techSignaturesLock.Unlock()
+	return nil
+}
+
+// parseTechSignatures decodes and compiles a list of technology signatures
+func parseTechSignatures(data []byte) ([]TechSignature, error) {
+	var signatures []TechSignature
+	if err := json.Unmarshal(data, &signatures); err != nil {
+		return nil, err
+	}
+
+	for i := range signatures {
+		sig := &signatures[i]
+		if sig.Name == "" {
+			return nil, fmt.Errorf("signature %d has no name", i)
+		}
+
+		sig.headers = make(map[string]*regexp.Regexp, len(sig.Headers))
+		for header, pattern := range sig.Headers {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("%s: header %s: %v", sig.Name, header, err)
+			}
+			sig.headers[http.CanonicalHeaderKey(header)] = re
+		}
+
+		for _, pattern := range sig.Body {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("%s: body: %v", sig.Name, err)
+			}
+			sig.body = append(sig.body, re)
+		}
+	}
+
+	return signatures, nil
+}
+
+// getTechSignatures returns the active signatures, loading the built-in ones on first use
+func getTechSignatures() ([]TechSignature, error) {
+	techSignaturesOnce.Do(func() {
+		signatures, err := parseTechSignatures(defaultTechSignatures)
+		techSignaturesLock.Lock()
+		techSignatures, techSignaturesErr = signatures, err
+		techSignaturesLock.Unlock()
+	})
+
+	techSignaturesLock.RLock()
+	defer techSignaturesLock.RUnlock()
+	return techSignatures, techSignaturesErr
+}
+
+// DetectTechnologies returns the sorted names of the technologies whose
+// signatures match the response headers and body
+func DetectTechnologies(headers http.Header, body []byte) []string {
+	signatures, err := getTechSignatures()
+	if err != nil {
+		return nil
+	}
+
+	cookies := make(map[string]bool)
+	for _, cookie := range (&http.Response{Header: headers}).Cookies() {
+		cookies[cookie.Name] = true
+	}
+
+	var found []string
+	for _, sig := range signatures {
+		if sig.matches(headers, cookies, body) {
+			found = append(found, sig.Name)
+		}
+	}
+
+	sort.Strings(found)
+	return found
+}
+
+// matches reports whether the signature matches a response
+func (sig *TechSignature) matches(headers http.Header, cookies map[string]bool, body []byte) bool {
+	for header, re := range sig.headers {
+		for _, value := range headers.Values(header) {
+			if re.MatchString(value) {
+				return true
+			}
+		}
+	}
+
+	for _, name := range sig.Cookies {
+		if cookies[name] {
+			return true
+		}
+	}
+
+	for _, re := range sig.body {
+		if re.Match(body) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,197 +1,121 @@
 package scanner
 
 import (
-	"crypto/tls"
-	"fmt"
-	"io"
 	"net"
-	"net/http"
-	"os"
-	"path/filepath"
-	"strings"
+	"strconv"
+	"sync"
 	"time"
 
-	"github.com/fatih/color"
+	"github.com/SayerLinux/sub/pkg/utils"
 )
 
 // ServiceInfo represents information about a service running on a subdomain
 type ServiceInfo struct {
-	Subdomain  string
-	IP         string
-	Port       int
-	Service    string
-	StatusCode int
-	Title      string
-	Server     string
+	Subdomain     string
+	IP            string
+	Port          int
+	Service       string
+	StatusCode    int
+	Title         string
+	Server        string
+	URL           string
+	FinalURL      string
+	Redirects     []Redirect
+	ContentLength int64
+	ContentType   string
+	BodyHash      string
+	FaviconHash   int32
+	Headers       map[string]string
+	Technologies  []string
+	Network       *IPInfo
+	Attribution   *Attribution
 }
 
-// CheckCommonPorts checks common ports on a subdomain
-func CheckCommonPorts(subdomain string, ip string) []ServiceInfo {
-	commonPorts := []struct {
-		Port    int
-		Service string
-	}{
-		{80, "HTTP"},
-		{443, "HTTPS"},
-		{21, "FTP"},
-		{22, "SSH"},
-		{25, "SMTP"},
-		{53, "DNS"},
-		{8080, "HTTP-ALT"},
-		{8443, "HTTPS-ALT"},
-	}
-
-	var results []ServiceInfo
-
-	for _, port := range commonPorts {
-		addr := fmt.Sprintf("%s:%d", ip, port.Port)
-		conn, err := net.DialTimeout("tcp", addr, 2*time.Second)
-		if err == nil {
-			conn.Close()
-			info := ServiceInfo{
-				Subdomain: subdomain,
-				IP:        ip,
-				Port:      port.Port,
-				Service:   port.Service,
-			}
-
-			// If HTTP or HTTPS, get more information
-			if port.Port == 80 || port.Port == 8080 {
-				getHTTPInfo(&info, false)
-			} else if port.Port == 443 || port.Port == 8443 {
-				getHTTPInfo(&info, true)
-			}
-
-			results = append(results, info)
-		}
-	}
-
-	return results
+// Port describes a port to check and the service expected on it
+type Port struct {
+	Port    int
+	Service string
 }
 
-// getHTTPInfo gets HTTP information from a service
-func getHTTPInfo(info *ServiceInfo, isHTTPS bool) {
-	var url string
-	if isHTTPS {
-		url = fmt.Sprintf("https://%s:%d", info.Subdomain, info.Port)
-	} else {
-		url = fmt.Sprintf("http://%s:%d", info.Subdomain, info.Port)
-	}
-
-	client := &http.Client{
-		Timeout: 5 * time.Second,
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		},
-	}
-
-	resp, err := client.Get(url)
-	if err != nil {
-		return
-	}
-	defer resp.Body.Close()
-
-	info.StatusCode = resp.StatusCode
-	info.Server = resp.Header.Get("Server")
-
-	// Extract title if possible
-	body, err := io.ReadAll(io.LimitReader(resp.Body, 10000))
-	if err == nil {
-		bodyStr := string(body)
-		titleStart := strings.Index(bodyStr, "<title>")
-		if titleStart != -1 {
-			titleEnd := strings.Index(bodyStr[titleStart:], "</title>")
-			if titleEnd != -1 {
-				title := bodyStr[titleStart+7 : titleStart+titleEnd]
-				info.Title = strings.TrimSpace(title)
-			}
-		}
-	}
+// CommonPorts are the ports checked on every subdomain
+var CommonPorts = []Port{
+	{80, "HTTP"},
+	{443, "HTTPS"},
+	{21, "FTP"},
+	{22, "SSH"},
+	{25, "SMTP"},
+	{53, "DNS"},
+	{8080, "HTTP-ALT"},
+	{8443, "HTTPS-ALT"},
 }
 
-// ExtractFiles attempts to extract files from a subdomain
-func ExtractFiles(subdomain string, outputDir string) error {
-	// Common file paths to check
-	commonPaths := []string{
-		"/robots.txt",
-		"/sitemap.xml",
-		"/.git/HEAD",
-		"/.env",
-		"/wp-config.php",
-		"/config.php",
-		"/admin/",
-		"/backup/",
-		"/database/",
-		"/api/",
-	}
-
-	// Create output directory for this subdomain
-	subdomainDir := filepath.Join(outputDir, subdomain)
-	err := os.MkdirAll(subdomainDir, 0755)
-	if err != nil {
-		return fmt.Errorf("failed to create output directory: %v", err)
+// CheckCommonPorts checks the common ports and any extra ports on a subdomain,
+// dialing up to threads ports at a time. Open ports are returned in the order
+// they are listed. Nothing is dialed when the subdomain or IP is out of scope.
+func CheckCommonPorts(subdomain string, ip string, threads int, extra ...Port) []ServiceInfo {
+	if !InScope(subdomain, ip) {
+		return nil
 	}
 
-	// Check each path
-	for _, path := range commonPaths {
-		url := fmt.Sprintf("http://%s%s", subdomain, path)
-		secureURL := fmt.Sprintf("https://%s%s", subdomain, path)
-
-		// Try HTTP first
-		if downloadFile(url, subdomainDir, path) {
-			fmt.Printf("%s Found: %s\n", color.GreenString("[+]"), url)
-			continue
+	ports := append([]Port{}, CommonPorts...)
+	for _, port := range extra {
+		known := false
+		for _, common := range CommonPorts {
+			known = known || common.Port == port.Port
 		}
-
-		// Try HTTPS if HTTP failed
-		if downloadFile(secureURL, subdomainDir, path) {
-			fmt.Printf("%s Found: %s\n", color.GreenString("[+]"), secureURL)
+		if !known {
+			ports = append(ports, port)
 		}
 	}
 
-	return nil
-}
-
-// downloadFile downloads a file from a URL
-func downloadFile(url string, outputDir string, path string) bool {
-	client := &http.Client{
-		Timeout: 5 * time.Second,
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		},
+	if threads < 1 {
+		threads = 1
 	}
 
-	resp, err := client.Get(url)
-	if err != nil || resp.StatusCode >= 400 {
-		if resp != nil {
-			resp.Body.Close()
-		}
-		return false
-	}
-	defer resp.Body.Close()
+	found := make([]*ServiceInfo, len(ports))
+	semaphore := make(chan struct{}, threads)
+	var wg sync.WaitGroup
+
+	for i, port := range ports {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int, port int, service string) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			addr := net.JoinHostPort(ip, strconv.Itoa(port))
+			conn, err := utils.DialTimeout("tcp", addr, 2*time.Second)
+			if err != nil {
+				return
+			}
+			conn.Close()
 
-	// Create file path
-	filePath := filepath.Join(outputDir, strings.TrimPrefix(path, "/"))
+			info := ServiceInfo{
+				Subdomain: subdomain,
+				IP:        ip,
+				Port:      port,
+				Service:   service,
+			}
 
-	// Create directory if needed
-	dir := filepath.Dir(filePath)
-	err = os.MkdirAll(dir, 0755)
-	if err != nil {
-		return false
-	}
+			// If HTTP or HTTPS, get more information
+			switch service {
+			case "HTTP", "HTTP-ALT":
+				probeHTTP(&info, false)
+			case "HTTPS", "HTTPS-ALT":
+				probeHTTP(&info, true)
+			}
 
-	// Create file
-	file, err := os.Create(filePath)
-	if err != nil {
-		return false
+			found[i] = &info
+		}(i, port.Port, port.Service)
 	}
-	defer file.Close()
+	wg.Wait()
 
-	// Write to file
-	_, err = io.Copy(file, resp.Body)
-	if err != nil {
-		return false
+	var results []ServiceInfo
+	for _, info := range found {
+		if info != nil {
+			results = append(results, *info)
+		}
 	}
 
-	return true
-}
\ No newline at end of file
+	return results
+}
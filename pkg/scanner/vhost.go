@@ -0,0 +1,209 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/SayerLinux/sub/pkg/utils"
+)
+
+// SourceVhost tags names found by virtual host discovery
+const SourceVhost = "vhost"
+
+// maxVhostBodySize is the part of each response compared with the baseline
+const maxVhostBodySize = 1 << 20
+
+// DefaultVhostPorts are the ports probed when no port is given
+var DefaultVhostPorts = []int{80, 443}
+
+// VhostConfig holds the virtual host discovery configuration. Each word is
+// tried as word.Domain, and the domain itself is tried too.
+type VhostConfig struct {
+	Domain  string
+	Words   []string
+	Ports   []int
+	Threads int
+}
+
+// VhostResult is a virtual host answering differently from the baseline
+type VhostResult struct {
+	Host          string `json:"host"`
+	IP            string `json:"ip"`
+	Port          int    `json:"port"`
+	Scheme        string `json:"scheme"`
+	StatusCode    int    `json:"status_code"`
+	ContentLength int    `json:"content_length"`
+	Title         string `json:"title,omitempty"`
+}
+
+// vhostTarget is an address and port serving HTTP, with the answer it gives
+// for a host it does not know
+type vhostTarget struct {
+	ip       string
+	port     int
+	scheme   string
+	client   *http.Client
+	baseline *httpResponse
+}
+
+// vhostJob is a host name to try on a target
+type vhostJob struct {
+	target *vhostTarget
+	host   string
+}
+
+// DiscoverVhosts sends requests with Host and SNI values built from the
+// wordlist to every address and port, and calls found for each host whose
+// response differs from the baseline for a random host. Ports that do not
+// answer over HTTP or HTTPS are skipped.
+func DiscoverVhosts(ips []string, config VhostConfig, found func(VhostResult)) {
+	ports := config.Ports
+	if len(ports) == 0 {
+		ports = DefaultVhostPorts
+	}
+	threads := config.Threads
+	if threads < 1 {
+		threads = 1
+	}
+
+	// Learn how each address answers for an unknown host
+	var targets []*vhostTarget
+	for _, ip := range ips {
+		if !InScope(ip) {
+			logOutOfScope(ip)
+			continue
+		}
+		for _, port := range ports {
+			targets = append(targets, newVhostTargets(ip, port, config.Domain)...)
+		}
+	}
+
+	names := []string{config.Domain}
+	for _, word := range config.Words {
+		names = append(names, strings.ToLower(word)+"."+config.Domain)
+	}
+
+	var hosts []string
+	for _, host := range names {
+		if !InScope(host) {
+			logOutOfScope(host)
+			continue
+		}
+		hosts = append(hosts, host)
+	}
+
+	jobs := make(chan vhostJob, threads)
+	var wg sync.WaitGroup
+	for i := 0; i < threads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				if result, ok := job.target.check(job.host); ok {
+					found(result)
+				}
+			}
+		}()
+	}
+
+	for _, target := range targets {
+		for _, host := range hosts {
+			jobs <- vhostJob{target: target, host: host}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// newVhostTargets fetches the baseline of an address and port over HTTP and
+// HTTPS and returns a target for each scheme that answers. TLS ports often
+// answer plain HTTP with an error page, so both schemes are kept.
+func newVhostTargets(ip string, port int, domain string) []*vhostTarget {
+	label, err := randomLabel()
+	if err != nil {
+		return nil
+	}
+	bogus := label + "." + domain
+
+	var targets []*vhostTarget
+	for _, scheme := range []string{"http", "https"} {
+		target := &vhostTarget{
+			ip:     ip,
+			port:   port,
+			scheme: scheme,
+			client: newVhostClient(ip, port),
+		}
+
+		baseline, err := fetchWith(target.client, target.url(bogus), maxVhostBodySize)
+		if err != nil {
+			continue
+		}
+		baseline.Body = bytes.ReplaceAll(baseline.Body, []byte(bogus), nil)
+		baseline.Location = strings.ReplaceAll(baseline.Location, bogus, "")
+		target.baseline = baseline
+		targets = append(targets, target)
+	}
+
+	return targets
+}
+
+// newVhostClient creates a client that connects to the address whatever host
+// the request is for, so the host name is only used for the Host header and
+// the TLS server name. Connections go through SOCKS5 proxies only, as HTTP
+// proxies would resolve the host name themselves.
+func newVhostClient(ip string, port int) *http.Client {
+	address := net.JoinHostPort(ip, strconv.Itoa(port))
+	config := httpSettings()
+
+	transport := newHTTPTransport(config, func(ctx context.Context, network, _ string) (net.Conn, error) {
+		ctx, cancel := context.WithTimeout(ctx, config.ConnectTimeout)
+		defer cancel()
+		return utils.DialContext(ctx, network, address)
+	})
+	transport.Proxy = nil
+	return newHTTPClient(config, transport)
+}
+
+// url returns the root URL of a host on the target
+func (t *vhostTarget) url(host string) string {
+	if t.scheme == "http" && t.port == 80 || t.scheme == "https" && t.port == 443 {
+		return fmt.Sprintf("%s://%s/", t.scheme, host)
+	}
+	return fmt.Sprintf("%s://%s:%d/", t.scheme, host, t.port)
+}
+
+// check requests a host on the target and reports it if the response differs
+// from the baseline
+func (t *vhostTarget) check(host string) (VhostResult, bool) {
+	resp, err := fetchWith(t.client, t.url(host), maxVhostBodySize)
+	if err != nil || !isVhostFound(host, resp, t.baseline) {
+		return VhostResult{}, false
+	}
+
+	title, _ := parseHTML(resp.Body)
+	return VhostResult{
+		Host:          host,
+		IP:            t.ip,
+		Port:          t.port,
+		Scheme:        t.scheme,
+		StatusCode:    resp.StatusCode,
+		ContentLength: len(resp.Body),
+		Title:         strings.TrimSpace(title),
+	}, true
+}
+
+// isVhostFound decides whether a response for a host is different from the
+// answer for an unknown host. Hosts echoed in the page or in a redirect are
+// removed first, as default pages often include the requested name.
+func isVhostFound(host string, resp *httpResponse, baseline *httpResponse) bool {
+	if strings.ReplaceAll(resp.Location, host, "") != baseline.Location {
+		return true
+	}
+	return !isSoftNotFound(host, resp, baseline)
+}
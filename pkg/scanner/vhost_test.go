@@ -0,0 +1,62 @@
+package scanner
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestDiscoverVhosts(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := strings.Split(r.Host, ":")[0]
+		switch {
+		case host == "admin.example.com":
+			fmt.Fprint(w, "<html><title>Admin</title>internal dashboard for the operations team</html>")
+		case r.TLS != nil && r.TLS.ServerName == "vpn.example.com":
+			http.Redirect(w, r, "https://vpn.example.com/login", http.StatusFound)
+		default:
+			fmt.Fprintf(w, "<html><title>Welcome</title>no site is configured for %s</html>", host)
+		}
+	})
+
+	for _, server := range []*httptest.Server{httptest.NewServer(handler), httptest.NewTLSServer(handler)} {
+		defer server.Close()
+
+		host, portValue, _ := net.SplitHostPort(server.Listener.Addr().String())
+		port, _ := strconv.Atoi(portValue)
+
+		var (
+			found []string
+			mutex sync.Mutex
+		)
+		DiscoverVhosts([]string{host}, VhostConfig{
+			Domain:  "example.com",
+			Words:   []string{"www", "admin", "vpn", "mail"},
+			Ports:   []int{port},
+			Threads: 4,
+		}, func(result VhostResult) {
+			mutex.Lock()
+			found = append(found, result.Scheme+" "+result.Host)
+			mutex.Unlock()
+		})
+
+		want := []string{"http admin.example.com"}
+		if server.TLS != nil {
+			want = []string{"https admin.example.com", "https vpn.example.com"}
+		}
+		if len(found) != len(want) {
+			t.Errorf("found %v, want %v", found, want)
+			continue
+		}
+		for _, name := range want {
+			if !containsString(found, name) {
+				t.Errorf("found %v, want %v", found, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package scanner
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"sync"
+
+	"github.com/SayerLinux/sub/pkg/utils"
+)
+
+// wildcardProbes is the number of random names resolved under each domain to
+// detect wildcard DNS. Several are used to catch round robin answers.
+const wildcardProbes = 3
+
+// randomLabel returns a random DNS label that is very unlikely to exist
+func randomLabel() (string, error) {
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// detectWildcard resolves random names under a domain and returns the
+// addresses they point to, or nil if the domain has no wildcard DNS. Domains
+// whose names are out of scope are not probed.
+func detectWildcard(domain string) map[string]bool {
+	var addresses map[string]bool
+	for i := 0; i < wildcardProbes; i++ {
+		label, err := randomLabel()
+		if err != nil {
+			return addresses
+		}
+
+		name := label + "." + domain
+		if !InScope(name) {
+			logOutOfScope("wildcard probes under " + domain)
+			return nil
+		}
+
+		ips, err := utils.Resolver().LookupHost(context.Background(), name)
+		if err != nil {
+			continue
+		}
+		if addresses == nil {
+			addresses = make(map[string]bool)
+		}
+		for _, ip := range ips {
+			addresses[ip] = true
+		}
+	}
+	return addresses
+}
+
+// detectWildcards runs wildcard detection on every target domain of the scan
+// in parallel and returns the wildcard addresses of the domains that have them
+func detectWildcards(domains []string, threads int) map[string]map[string]bool {
+	wildcards := make(map[string]map[string]bool)
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	limit := make(chan struct{}, threads)
+
+	for _, domain := range domains {
+		wg.Add(1)
+		limit <- struct{}{}
+		go func(domain string) {
+			defer wg.Done()
+			defer func() { <-limit }()
+
+			if addresses := detectWildcard(domain); addresses != nil {
+				mutex.Lock()
+				wildcards[domain] = addresses
+				mutex.Unlock()
+			}
+		}(domain)
+	}
+
+	wg.Wait()
+	return wildcards
+}
+
+// isWildcardAnswer reports whether every address a name resolved to is one
+// of the wildcard addresses of its domain
+func isWildcardAnswer(wildcard map[string]bool, addresses []string) bool {
+	if len(wildcard) == 0 || len(addresses) == 0 {
+		return false
+	}
+	for _, address := range addresses {
+		if !wildcard[address] {
+			return false
+		}
+	}
+	return true
+}
+
+// wildcardAddresses returns the sorted wildcard addresses of a domain
+func wildcardAddresses(wildcard map[string]bool) []string {
+	addresses := make([]string, 0, len(wildcard))
+	for address := range wildcard {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	return addresses
+}
@@ -0,0 +1,196 @@
+package scanner
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/SayerLinux/sub/pkg/utils"
+)
+
+// startWildcardDNS serves a zone answering every A query with 192.0.2.1 and
+// makes it the active resolver. It returns the names queried so far.
+func startWildcardDNS(t *testing.T) func() []string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mutex   sync.Mutex
+		queries []string
+	)
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			query := buf[:n]
+
+			// Read the question name to find where the question ends
+			var labels []string
+			i := 12
+			for i < n && query[i] != 0 {
+				labels = append(labels, string(query[i+1:i+1+int(query[i])]))
+				i += 1 + int(query[i])
+			}
+			end := i + 5
+			if end > n {
+				continue
+			}
+			qtype := binary.BigEndian.Uint16(query[i+1:])
+
+			mutex.Lock()
+			queries = append(queries, strings.Join(labels, "."))
+			mutex.Unlock()
+
+			response := append([]byte(nil), query[:end]...)
+			binary.BigEndian.PutUint16(response[2:], 0x8180)
+			binary.BigEndian.PutUint16(response[8:], 0)
+			binary.BigEndian.PutUint16(response[10:], 0)
+			if qtype == 1 {
+				binary.BigEndian.PutUint16(response[6:], 1)
+				response = append(response, 0xc0, 0x0c, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4, 192, 0, 2, 1)
+			} else {
+				binary.BigEndian.PutUint16(response[6:], 0)
+			}
+			conn.WriteTo(response, addr)
+		}
+	}()
+
+	if err := utils.LoadResolvers(conn.LocalAddr().String()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		utils.LoadResolvers()
+		conn.Close()
+	})
+
+	return func() []string {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]string(nil), queries...)
+	}
+}
+
+func TestDetectWildcardRespectsScope(t *testing.T) {
+	queried := startWildcardDNS(t)
+
+	scope, err := parseScope(strings.NewReader("example.com\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scopeLock.Lock()
+	activeScope = scope
+	scopeLock.Unlock()
+	defer func() {
+		scopeLock.Lock()
+		activeScope = nil
+		scopeLock.Unlock()
+	}()
+
+	wildcard := detectWildcard("example.com")
+	if !reflect.DeepEqual(wildcard, map[string]bool{"192.0.2.1": true}) {
+		t.Errorf("detectWildcard(example.com) = %v", wildcard)
+	}
+
+	before := len(queried())
+	if wildcard := detectWildcard("example.org"); wildcard != nil {
+		t.Errorf("detectWildcard(example.org) = %v, want nil for an out of scope domain", wildcard)
+	}
+	for _, name := range queried()[before:] {
+		t.Errorf("out of scope name %s was queried", name)
+	}
+}
+
+func TestIsWildcardAnswer(t *testing.T) {
+	wildcard := map[string]bool{"192.0.2.1": true, "192.0.2.2": true}
+
+	tests := []struct {
+		wildcard  map[string]bool
+		addresses []string
+		want      bool
+	}{
+		{wildcard, []string{"192.0.2.1"}, true},
+		{wildcard, []string{"192.0.2.2", "192.0.2.1"}, true},
+		{wildcard, []string{"192.0.2.1", "198.51.100.7"}, false},
+		{wildcard, nil, false},
+		{nil, []string{"192.0.2.1"}, false},
+	}
+
+	for _, test := range tests {
+		if got := isWildcardAnswer(test.wildcard, test.addresses); got != test.want {
+			t.Errorf("isWildcardAnswer(%v) = %v, want %v", test.addresses, got, test.want)
+		}
+	}
+}
+
+func TestBruteForceJobsInterleaveTargets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	if err := os.WriteFile(path, []byte("www\napi\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewScanner(Config{Targets: []string{"example.com", "example.org"}, Wordlist: path})
+	if err := s.openWordlist(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.words.Close()
+	s.AddCandidate("api.example.org", "crtsh")
+
+	jobs := make(chan scanJob, 10)
+	s.sendBruteForceJobs(jobs)
+	close(jobs)
+
+	var names []string
+	for job := range jobs {
+		names = append(names, job.subdomain)
+	}
+	want := []string{"www.example.com", "www.example.org", "api.example.com", "api.example.org"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("jobs = %v, want %v", names, want)
+	}
+}
+
+func TestApexOf(t *testing.T) {
+	s := NewScanner(Config{Targets: []string{"example.com", "dev.example.com", "example.org"}})
+
+	tests := map[string]string{
+		"www.example.com":     "example.com",
+		"api.dev.example.com": "dev.example.com",
+		"example.org":         "example.org",
+		"www.example.net":     "",
+	}
+	for name, want := range tests {
+		if got := s.apexOf(name); got != want {
+			t.Errorf("apexOf(%s) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestWriteResultsGroupsByDomain(t *testing.T) {
+	results := []ScanResult{
+		{Subdomain: "www.example.org", Domain: "example.org", IP: "192.0.2.1", Found: true},
+		{Subdomain: "www.example.com", Domain: "example.com", IP: "192.0.2.2", Found: true},
+		{Subdomain: "nope.example.com", Domain: "example.com"},
+		{Subdomain: "api.example.org", Domain: "example.org", IP: "192.0.2.3", Found: true},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteResults(&buf, "example.com, example.org", "csv", results); err != nil {
+		t.Fatal(err)
+	}
+	body := buf.String()[strings.Index(buf.String(), "# Domain"):]
+	want := "# Domain: example.com\nwww.example.com,192.0.2.2\n\n# Domain: example.org\nwww.example.org,192.0.2.1\napi.example.org,192.0.2.3\n"
+	if body != want {
+		t.Errorf("csv = %q, want %q", body, want)
+	}
+}
@@ -1,7 +1,11 @@
 package scanner
 
 import (
+	"bufio"
+	"bytes"
+	"embed"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -9,6 +13,61 @@ import (
 	"github.com/SayerLinux/sub/pkg/utils"
 )
 
+//go:embed data/wordlists
+var builtinWordlistFiles embed.FS
+
+// builtinWordlists maps the names of the wordlists embedded in the binary to
+// their files
+var builtinWordlists = map[string]string{
+	"small":  "data/wordlists/small.txt",
+	"medium": "data/wordlists/medium.txt",
+	"large":  "data/wordlists/large.txt.gz",
+}
+
+// BuiltinWordlistNames lists the embedded wordlists from smallest to largest
+var BuiltinWordlistNames = []string{"small", "medium", "large"}
+
+// DefaultWordlist is the built-in wordlist used when none is given
+const DefaultWordlist = "medium"
+
+// OpenWordlist opens a wordlist file for streaming, or a built-in wordlist
+// when given its name and no file of that name exists
+func OpenWordlist(name string) (*utils.WordlistReader, error) {
+	if isBuiltinWordlist(name) {
+		data, err := builtinWordlistFiles.ReadFile(builtinWordlists[name])
+		if err != nil {
+			return nil, fmt.Errorf("failed to open built-in wordlist %s: %v", name, err)
+		}
+		return utils.NewWordlistReader(bytes.NewReader(data), int64(len(data)))
+	}
+	return utils.OpenWordlist(name)
+}
+
+// isBuiltinWordlist reports whether a name refers to a built-in wordlist,
+// which it does unless a file of that name exists
+func isBuiltinWordlist(name string) bool {
+	if _, ok := builtinWordlists[name]; !ok {
+		return false
+	}
+	_, err := os.Stat(name)
+	return os.IsNotExist(err)
+}
+
+// LoadWordlist loads a wordlist file or built-in wordlist into memory
+func LoadWordlist(name string) ([]string, error) {
+	reader, err := OpenWordlist(name)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	var words []string
+	for reader.Scan() {
+		words = append(words, reader.Word())
+	}
+	return words, reader.Err()
+}
+
 // DefaultWordlistPath is the path to the default wordlist
 const DefaultWordlistPath = "wordlists/default.txt"
 
@@ -27,32 +86,24 @@ func NewWordlistManager(wordlistPath string, logger *utils.Logger) *WordlistMana
 	}
 }
 
-// Load loads the wordlist
+// Load loads the wordlist, a file or a built-in wordlist. Without a path the
+// default wordlist file is used if it exists next to the working directory or
+// the executable, and the embedded default list otherwise.
 func (wm *WordlistManager) Load() error {
-	// If no wordlist path is provided, use the default
 	if wm.wordlistPath == "" {
-		// Check if the default wordlist exists
-		if _, err := os.Stat(DefaultWordlistPath); os.IsNotExist(err) {
-			// Try to find the default wordlist in the executable directory
-			execPath, err := os.Executable()
-			if err != nil {
-				return fmt.Errorf("failed to get executable path: %v", err)
-			}
-			execDir := filepath.Dir(execPath)
-			defaultPath := filepath.Join(execDir, DefaultWordlistPath)
-
-			if _, err := os.Stat(defaultPath); os.IsNotExist(err) {
-				return fmt.Errorf("default wordlist not found at %s or %s", DefaultWordlistPath, defaultPath)
-			}
-
-			wm.wordlistPath = defaultPath
-		} else {
+		wm.wordlistPath = DefaultWordlist
+		if _, err := os.Stat(DefaultWordlistPath); err == nil {
 			wm.wordlistPath = DefaultWordlistPath
+		} else if execPath, err := os.Executable(); err == nil {
+			defaultPath := filepath.Join(filepath.Dir(execPath), DefaultWordlistPath)
+			if _, err := os.Stat(defaultPath); err == nil {
+				wm.wordlistPath = defaultPath
+			}
 		}
 	}
 
 	wm.logger.Debug("Loading wordlist from %s", wm.wordlistPath)
-	words, err := utils.LoadWordlist(wm.wordlistPath)
+	words, err := LoadWordlist(wm.wordlistPath)
 	if err != nil {
 		return err
 	}
@@ -82,4 +133,150 @@ func (wm *WordlistManager) GenerateSubdomains(domain string) []string {
 	}
 
 	return subdomains
-}
\ No newline at end of file
+}
+
+// ValidateWord checks that a wordlist entry can be put in front of a domain.
+// Each label holds 1 to 63 letters, digits, hyphens or underscores and does
+// not start or end with a hyphen, and the entry is at most 253 characters.
+func ValidateWord(word string) error {
+	if len(word) > 253 {
+		return fmt.Errorf("longer than 253 characters")
+	}
+
+	for _, label := range strings.Split(word, ".") {
+		switch {
+		case label == "":
+			return fmt.Errorf("empty label")
+		case len(label) > 63:
+			return fmt.Errorf("label %q longer than 63 characters", label)
+		case strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-"):
+			return fmt.Errorf("label %q starts or ends with a hyphen", label)
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+				return fmt.Errorf("invalid character %q", c)
+			}
+		}
+	}
+	return nil
+}
+
+// WordlistStats summarises the entries of a wordlist
+type WordlistStats struct {
+	Entries       int
+	Unique        int
+	Duplicates    int
+	Uppercase     int
+	Invalid       int
+	MultiLabel    int
+	WithDigits    int
+	WithHyphens   int
+	MinLength     int
+	MaxLength     int
+	AverageLength float64
+}
+
+// ComputeWordlistStats reads a wordlist to its end and summarises it.
+// Entries differing only in case count as duplicates.
+func ComputeWordlistStats(reader *utils.WordlistReader) (WordlistStats, error) {
+	var stats WordlistStats
+	seen := make(map[string]bool)
+	total := 0
+	for reader.Scan() {
+		word := reader.Word()
+		stats.Entries++
+		total += len(word)
+		if stats.MinLength == 0 || len(word) < stats.MinLength {
+			stats.MinLength = len(word)
+		}
+		if len(word) > stats.MaxLength {
+			stats.MaxLength = len(word)
+		}
+
+		lower := strings.ToLower(word)
+		if seen[lower] {
+			stats.Duplicates++
+		}
+		seen[lower] = true
+		if lower != word {
+			stats.Uppercase++
+		}
+		if ValidateWord(word) != nil {
+			stats.Invalid++
+		}
+		if strings.Contains(word, ".") {
+			stats.MultiLabel++
+		}
+		if strings.ContainsAny(word, "0123456789") {
+			stats.WithDigits++
+		}
+		if strings.Contains(word, "-") {
+			stats.WithHyphens++
+		}
+	}
+	if err := reader.Err(); err != nil {
+		return stats, err
+	}
+
+	stats.Unique = len(seen)
+	if stats.Entries > 0 {
+		stats.AverageLength = float64(total) / float64(stats.Entries)
+	}
+	return stats, nil
+}
+
+// MergeOptions controls how wordlists are combined
+type MergeOptions struct {
+	Lowercase   bool
+	DropInvalid bool
+}
+
+// MergeStats counts what happened to the entries while merging
+type MergeStats struct {
+	Read       int
+	Written    int
+	Duplicates int
+	Invalid    int
+}
+
+// MergeWordlists writes the entries of the wordlists to w one per line, in
+// order and without duplicates
+func MergeWordlists(w io.Writer, names []string, options MergeOptions) (MergeStats, error) {
+	var stats MergeStats
+	seen := make(map[string]bool)
+	writer := bufio.NewWriter(w)
+
+	for _, name := range names {
+		reader, err := OpenWordlist(name)
+		if err != nil {
+			return stats, err
+		}
+
+		for reader.Scan() {
+			word := reader.Word()
+			stats.Read++
+			if options.Lowercase {
+				word = strings.ToLower(word)
+			}
+			if options.DropInvalid && ValidateWord(word) != nil {
+				stats.Invalid++
+				continue
+			}
+			if seen[word] {
+				stats.Duplicates++
+				continue
+			}
+			seen[word] = true
+			stats.Written++
+			writer.WriteString(word + "\n")
+		}
+
+		err = reader.Err()
+		reader.Close()
+		if err != nil {
+			return stats, err
+		}
+	}
+
+	return stats, writer.Flush()
+}
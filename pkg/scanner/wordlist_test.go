@@ -0,0 +1,136 @@
+package scanner
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/SayerLinux/sub/pkg/utils"
+)
+
+func TestValidateWord(t *testing.T) {
+	valid := []string{"www", "api-v2", "_dmarc", "dev.api", "A1", "x"}
+	invalid := []string{"-www", "www-", "dev..api", "*.api", "www mail", "ünicode", strings.Repeat("a", 64)}
+
+	for _, word := range valid {
+		if err := ValidateWord(word); err != nil {
+			t.Errorf("ValidateWord(%q) = %v, want nil", word, err)
+		}
+	}
+	for _, word := range invalid {
+		if err := ValidateWord(word); err == nil {
+			t.Errorf("ValidateWord(%q) accepted an invalid word", word)
+		}
+	}
+}
+
+func TestBuiltinWordlists(t *testing.T) {
+	previous := 0
+	for _, name := range BuiltinWordlistNames {
+		words, err := LoadWordlist(name)
+		if err != nil {
+			t.Fatalf("LoadWordlist(%s) failed: %v", name, err)
+		}
+		if len(words) <= previous {
+			t.Errorf("%s has %d entries, not more than the previous list", name, len(words))
+		}
+		previous = len(words)
+
+		for _, word := range words {
+			if err := ValidateWord(word); err != nil {
+				t.Errorf("%s: invalid entry %q: %v", name, word, err)
+			}
+		}
+	}
+}
+
+func TestWordlistManager(t *testing.T) {
+	// The test runs without a wordlists directory, so the embedded list is used
+	wm := NewWordlistManager("", utils.NewLogger(false, nil))
+	if err := wm.Load(); err != nil {
+		t.Fatal(err)
+	}
+	want, err := LoadWordlist(DefaultWordlist)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(wm.GetWordlist(), want) {
+		t.Errorf("Load() without a path read %d words, want the %d of %s", len(wm.GetWordlist()), len(want), DefaultWordlist)
+	}
+
+	path := filepath.Join(t.TempDir(), "words.txt")
+	if err := os.WriteFile(path, []byte("www\n# comment\napi\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wm = NewWordlistManager(path, utils.NewLogger(false, nil))
+	if err := wm.Load(); err != nil {
+		t.Fatal(err)
+	}
+	got := wm.GenerateSubdomains("https://example.com/login")
+	if !reflect.DeepEqual(got, []string{"www.example.com", "api.example.com"}) {
+		t.Errorf("GenerateSubdomains() = %v", got)
+	}
+}
+
+func TestMergeWordlists(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.txt")
+	second := filepath.Join(dir, "second.txt")
+	os.WriteFile(first, []byte("www\nAPI\n# comment\nbad-\n"), 0644)
+	os.WriteFile(second, []byte("api\nmail\nwww\n"), 0644)
+
+	var buf bytes.Buffer
+	stats, err := MergeWordlists(&buf, []string{first, second}, MergeOptions{Lowercase: true, DropInvalid: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "www\napi\nmail\n"; got != want {
+		t.Errorf("merged = %q, want %q", got, want)
+	}
+	if want := (MergeStats{Read: 6, Written: 3, Duplicates: 2, Invalid: 1}); stats != want {
+		t.Errorf("stats = %+v, want %+v", stats, want)
+	}
+
+	buf.Reset()
+	if _, err := MergeWordlists(&buf, []string{first}, MergeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "www\nAPI\nbad-\n"; got != want {
+		t.Errorf("merged as is = %q, want %q", got, want)
+	}
+}
+
+func TestComputeWordlistStats(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	os.WriteFile(path, []byte("www\nWWW\ndev-1\napi.v2\n-bad\n"), 0644)
+
+	reader, err := OpenWordlist(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	stats, err := ComputeWordlistStats(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := WordlistStats{
+		Entries:       5,
+		Unique:        4,
+		Duplicates:    1,
+		Uppercase:     1,
+		Invalid:       1,
+		MultiLabel:    1,
+		WithDigits:    2,
+		WithHyphens:   2,
+		MinLength:     3,
+		MaxLength:     6,
+		AverageLength: 4.2,
+	}
+	if stats != want {
+		t.Errorf("stats = %+v, want %+v", stats, want)
+	}
+}
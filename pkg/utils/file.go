@@ -2,46 +2,224 @@ package utils
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
-// LoadWordlist loads a wordlist from a file
+// LoadWordlist loads a small wordlist, plain or gzip compressed, from a file
+// into memory. Large lists should be streamed with OpenWordlist.
 func LoadWordlist(path string) ([]string, error) {
-	file, err := os.Open(path)
+	reader, err := OpenWordlist(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open wordlist file: %v", err)
+		return nil, err
 	}
-	defer file.Close()
+	defer reader.Close()
 
 	var words []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		word := strings.TrimSpace(scanner.Text())
-		// Skip empty lines and comments
-		if word != "" && !strings.HasPrefix(word, "#") {
-			words = append(words, word)
-		}
+	for reader.Scan() {
+		words = append(words, reader.Word())
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading wordlist file: %v", err)
+	if err := reader.Err(); err != nil {
+		return nil, err
 	}
 
 	return words, nil
 }
 
-// LoadTargets loads targets from a file or returns a single target
-func LoadTargets(target string) ([]string, error) {
+// Target represents a host to scan with an optional explicit port
+type Target struct {
+	Host   string
+	Port   int
+	Scheme string
+}
+
+// LoadTargets loads targets from stdin ("-"), from a file or returns a single target
+func LoadTargets(target string) ([]Target, error) {
+	if target == "-" {
+		return ReadTargets(os.Stdin)
+	}
+
 	// Check if the target is a file
-	if _, err := os.Stat(target); err == nil {
-		return LoadWordlist(target)
+	if info, err := os.Stat(target); err == nil && !info.IsDir() {
+		file, err := os.Open(target)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open targets file: %v", err)
+		}
+		defer file.Close()
+
+		return ReadTargets(file)
 	}
 
 	// Return the target as a single item
-	return []string{target}, nil
+	parsed, err := ParseTarget(target)
+	if err != nil {
+		return nil, err
+	}
+	return []Target{parsed}, nil
+}
+
+// ReadTargets reads a list of targets. Plain lists, CSV files such as the
+// brute force output, JSON arrays and JSON lines are accepted, and entries may
+// be host names, host:port pairs or URLs. Duplicates are removed.
+func ReadTargets(reader io.Reader) ([]Target, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("error reading targets: %v", err)
+	}
+
+	var entries []string
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		entries, err = parseJSONTargets(trimmed)
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		entries, err = parseJSONLinesTargets(trimmed)
+	default:
+		entries = parseTextTargets(trimmed)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []Target
+	seen := make(map[Target]bool)
+	for _, entry := range entries {
+		target, err := ParseTarget(entry)
+		if err != nil || seen[target] {
+			continue
+		}
+		seen[target] = true
+		targets = append(targets, target)
+	}
+
+	return targets, nil
+}
+
+// parseTextTargets reads one target per line, taking the first column of CSV lines
+func parseTextTargets(data []byte) []string {
+	var entries []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		// Skip empty lines and comments
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		field := strings.TrimSpace(strings.Split(line, ",")[0])
+		field = strings.Trim(field, `"`)
+
+		// Skip CSV header rows
+		switch strings.ToLower(field) {
+		case "subdomain", "host", "domain", "name", "url":
+			continue
+		}
+
+		entries = append(entries, field)
+	}
+
+	return entries
+}
+
+// parseJSONTargets reads a JSON array of strings or of result objects
+func parseJSONTargets(data []byte) ([]string, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("invalid JSON targets: %v", err)
+	}
+
+	var entries []string
+	for _, item := range items {
+		if entry := jsonTarget(item); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+// parseJSONLinesTargets reads one JSON result object per line
+func parseJSONLinesTargets(data []byte) ([]string, error) {
+	var entries []string
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for decoder.More() {
+		var item json.RawMessage
+		if err := decoder.Decode(&item); err != nil {
+			return nil, fmt.Errorf("invalid JSON targets: %v", err)
+		}
+		if entry := jsonTarget(item); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+// jsonTarget extracts the target from a JSON string or from the first known
+// field of a result object
+func jsonTarget(item json.RawMessage) string {
+	var value string
+	if err := json.Unmarshal(item, &value); err == nil {
+		return value
+	}
+
+	var object map[string]interface{}
+	if err := json.Unmarshal(item, &object); err != nil {
+		return ""
+	}
+
+	for _, key := range []string{"subdomain", "host", "url", "domain", "name"} {
+		if value, ok := object[key].(string); ok && value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// ParseTarget extracts the host and optional port from a host name,
+// host:port pair or URL
+func ParseTarget(entry string) (Target, error) {
+	entry = strings.TrimSpace(entry)
+	if entry == "" {
+		return Target{}, fmt.Errorf("empty target")
+	}
+
+	// Parse everything as a URL so ports, paths and IPv6 literals are handled alike
+	raw := entry
+	if !strings.Contains(raw, "://") {
+		raw = "scheme://" + raw
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return Target{}, fmt.Errorf("invalid target %q: %v", entry, err)
+	}
+
+	target := Target{Host: strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")}
+	if parsed.Scheme != "scheme" {
+		target.Scheme = strings.ToLower(parsed.Scheme)
+	}
+	if target.Host == "" {
+		return Target{}, fmt.Errorf("invalid target %q: missing host", entry)
+	}
+
+	if port := parsed.Port(); port != "" {
+		target.Port, err = strconv.Atoi(port)
+		if err != nil || target.Port < 1 || target.Port > 65535 {
+			return Target{}, fmt.Errorf("invalid target %q: bad port", entry)
+		}
+	}
+
+	return target, nil
 }
 
 // EnsureDirectory ensures that a directory exists
@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		entry string
+		want  Target
+		err   bool
+	}{
+		{"www.example.com", Target{Host: "www.example.com"}, false},
+		{"  WWW.Example.COM.  ", Target{Host: "www.example.com"}, false},
+		{"api.example.com:8443", Target{Host: "api.example.com", Port: 8443}, false},
+		{"https://app.example.com/login?next=/", Target{Host: "app.example.com", Scheme: "https"}, false},
+		{"HTTP://app.example.com:8080/", Target{Host: "app.example.com", Port: 8080, Scheme: "http"}, false},
+		{"[2001:db8::1]:8080", Target{Host: "2001:db8::1", Port: 8080}, false},
+		{"192.0.2.10:22", Target{Host: "192.0.2.10", Port: 22}, false},
+		{"", Target{}, true},
+		{"https://", Target{}, true},
+		{"host.example.com:0", Target{}, true},
+		{"host.example.com:70000", Target{}, true},
+		{"host.example.com:http", Target{}, true},
+	}
+
+	for _, test := range tests {
+		got, err := ParseTarget(test.entry)
+		if (err != nil) != test.err {
+			t.Errorf("ParseTarget(%q) error = %v, want error %v", test.entry, err, test.err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("ParseTarget(%q) = %+v, want %+v", test.entry, got, test.want)
+		}
+	}
+}
+
+func TestReadTargets(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []Target
+	}{
+		{
+			"text",
+			"# targets\nwww.example.com\n\n  api.example.com:8443  \n# www.skipped.com\nWWW.example.com\nhttps://api.example.com:8443/\nbad:port:99999\n",
+			[]Target{
+				{Host: "www.example.com"},
+				{Host: "api.example.com", Port: 8443},
+				{Host: "api.example.com", Port: 8443, Scheme: "https"},
+			},
+		},
+		{
+			"brute force csv",
+			"Subdomain,IP\n\"www.example.com\",192.0.2.1\nmail.example.com,192.0.2.2\nwww.example.com,192.0.2.1\n",
+			[]Target{{Host: "www.example.com"}, {Host: "mail.example.com"}},
+		},
+		{
+			"json strings",
+			`["www.example.com", "http://dev.example.com:8080", "www.example.com"]`,
+			[]Target{{Host: "www.example.com"}, {Host: "dev.example.com", Port: 8080, Scheme: "http"}},
+		},
+		{
+			"json results",
+			`[{"subdomain": "www.example.com", "ip": "192.0.2.1"}, {"url": "https://shop.example.com"}, {"ip": "192.0.2.9"}]`,
+			[]Target{{Host: "www.example.com"}, {Host: "shop.example.com", Scheme: "https"}},
+		},
+		{
+			"json lines",
+			"{\"host\": \"a.example.com\", \"port\": 443}\n{\"host\": \"b.example.com\"}\n{\"host\": \"a.example.com\"}\n",
+			[]Target{{Host: "a.example.com"}, {Host: "b.example.com"}},
+		},
+	}
+
+	for _, test := range tests {
+		got, err := ReadTargets(strings.NewReader(test.input))
+		if err != nil {
+			t.Errorf("%s: ReadTargets() failed: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: ReadTargets() = %+v, want %+v", test.name, got, test.want)
+		}
+	}
+
+	if _, err := ReadTargets(strings.NewReader(`["unterminated"`)); err == nil {
+		t.Error("ReadTargets() accepted invalid JSON")
+	}
+}
+
+func TestLoadTargets(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "hosts.txt")
+	if err := os.WriteFile(path, []byte("a.example.com\nb.example.com:8080\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadTargets(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Target{{Host: "a.example.com"}, {Host: "b.example.com", Port: 8080}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadTargets(file) = %+v, want %+v", got, want)
+	}
+
+	// Anything that is not a file is a single target
+	got, err = LoadTargets("https://c.example.com:8443")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []Target{{Host: "c.example.com", Port: 8443, Scheme: "https"}}) {
+		t.Errorf("LoadTargets(url) = %+v", got)
+	}
+
+	if _, err := LoadTargets(filepath.Join(dir, "missing.txt:99999")); err == nil {
+		t.Error("LoadTargets() accepted an invalid target")
+	}
+}
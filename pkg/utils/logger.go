@@ -100,4 +100,11 @@ func (l *Logger) FileResult(subdomain string, filePath string, success bool, siz
 		fmt.Printf("%s %s\n", color.YellowString("[FILE]"), message)
 		l.writeToFile("FILE", message)
 	}
+}
+
+// SecretResult logs a secret found in an extracted file
+func (l *Logger) SecretResult(subdomain string, filePath string, line int, ruleID string, secret string) {
+	message := fmt.Sprintf("%s - %s:%d - %s - %s", subdomain, filePath, line, ruleID, secret)
+	fmt.Printf("%s %s\n", color.RedString("[SECRET]"), message)
+	l.writeToFile("SECRET", message)
 }
\ No newline at end of file
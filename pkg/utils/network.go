@@ -3,25 +3,33 @@ package utils
 import (
 	"context"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
+var (
+	httpClientSource func() *http.Client
+	httpClientLock   sync.RWMutex
+)
+
 // IsValidDomain checks if a domain is valid
 func IsValidDomain(domain string) bool {
 	// Simple validation to check if the domain has at least one dot
 	// and doesn't contain invalid characters
-	return strings.Contains(domain, ".") && 
-		!strings.Contains(domain, " ") && 
-		!strings.Contains(domain, "http://") && 
+	return strings.Contains(domain, ".") &&
+		!strings.Contains(domain, " ") &&
+		!strings.Contains(domain, "http://") &&
 		!strings.Contains(domain, "https://")
 }
 
 // ResolveDomain resolves a domain to its IP address
 func ResolveDomain(domain string) (string, error) {
-	ips, err := net.LookupHost(domain)
+	ips, err := Resolver().LookupHost(context.Background(), domain)
 	if err != nil {
 		return "", err
 	}
@@ -35,8 +43,8 @@ func ResolveDomain(domain string) (string, error) {
 
 // CheckPort checks if a port is open on a host
 func CheckPort(host string, port int, timeout time.Duration) bool {
-	address := fmt.Sprintf("%s:%d", host, port)
-	conn, err := net.DialTimeout("tcp", address, timeout)
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	conn, err := DialTimeout("tcp", address, timeout)
 	if err != nil {
 		return false
 	}
@@ -44,32 +52,56 @@ func CheckPort(host string, port int, timeout time.Duration) bool {
 	return true
 }
 
-// GetHTTPInfo gets information about an HTTP server
-func GetHTTPInfo(url string, timeout time.Duration) (int, string, map[string][]string, error) {
-	client := &http.Client{
-		Timeout: timeout,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			// Don't follow redirects
-			return http.ErrUseLastResponse
-		},
-	}
+// SetHTTPClient makes the HTTP helpers send their requests with the client
+// returned by source. The scanner package sets it to its shared HTTP engine.
+func SetHTTPClient(source func() *http.Client) {
+	httpClientLock.Lock()
+	httpClientSource = source
+	httpClientLock.Unlock()
+}
 
-	req, err := http.NewRequest("GET", url, nil)
+// httpGet sends a GET request with the client of the HTTP helpers. Redirects
+// are returned, not followed.
+func httpGet(url string, timeout time.Duration) (*http.Response, context.CancelFunc, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return 0, "", nil, err
+		cancel()
+		return nil, nil, err
 	}
 
-	// Set a user agent to avoid being blocked
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
-
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
-	req = req.WithContext(ctx)
+	httpClientLock.RLock()
+	source := httpClientSource
+	httpClientLock.RUnlock()
+
+	var client *http.Client
+	if source != nil {
+		client = source()
+	} else {
+		req.Header.Set("User-Agent", DefaultUserAgent)
+		client = &http.Client{
+			Transport: NewTransport(false),
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+	}
 
 	resp, err := client.Do(req)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	return resp, cancel, nil
+}
+
+// GetHTTPInfo gets information about an HTTP server
+func GetHTTPInfo(url string, timeout time.Duration) (int, string, map[string][]string, error) {
+	resp, cancel, err := httpGet(url, timeout)
 	if err != nil {
 		return 0, "", nil, err
 	}
+	defer cancel()
 	defer resp.Body.Close()
 
 	return resp.StatusCode, resp.Status, resp.Header, nil
@@ -77,36 +109,19 @@ func GetHTTPInfo(url string, timeout time.Duration) (int, string, map[string][]s
 
 // GetHTTPTitle gets the title of an HTTP page
 func GetHTTPTitle(url string, timeout time.Duration) (string, error) {
-	client := &http.Client{
-		Timeout: timeout,
-	}
-
-	req, err := http.NewRequest("GET", url, nil)
+	resp, cancel, err := httpGet(url, timeout)
 	if err != nil {
 		return "", err
 	}
-
-	// Set a user agent to avoid being blocked
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
-
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
-	req = req.WithContext(ctx)
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
 	defer resp.Body.Close()
 
 	// Read up to 8KB of the response body to look for the title
-	buf := make([]byte, 8192)
-	n, err := resp.Body.Read(buf)
-	if err != nil && err.Error() != "EOF" {
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 8192))
+	if err != nil {
 		return "", err
 	}
-
-	body := string(buf[:n])
+	body := string(data)
 
 	// Extract title using a simple string search
 	titleStart := strings.Index(body, "<title>")
@@ -122,4 +137,4 @@ func GetHTTPTitle(url string, timeout time.Duration) (string, error) {
 
 	title := body[titleStart : titleStart+titleEnd]
 	return strings.TrimSpace(title), nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,162 @@
+package utils
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// ProxyPool rotates connections over a list of HTTP and SOCKS5 proxies
+type ProxyPool struct {
+	proxies []*url.URL
+	socks   []proxy.ContextDialer
+	next    uint32
+	nextTCP uint32
+}
+
+var (
+	activeProxies *ProxyPool
+	proxiesLock   sync.RWMutex
+)
+
+// LoadProxies parses proxy URLs (http://, https:// or socks5://) and routes
+// all web traffic through them in turn. Each value may also be a file with
+// one proxy per line. Port checks go through the SOCKS5 proxies of the list.
+func LoadProxies(values ...string) error {
+	pool, err := ParseProxies(values...)
+	if err != nil {
+		return err
+	}
+
+	proxiesLock.Lock()
+	activeProxies = pool
+	proxiesLock.Unlock()
+	return nil
+}
+
+// ParseProxies creates a proxy pool from proxy URLs and files of proxy URLs.
+// It returns nil if no proxy is given.
+func ParseProxies(values ...string) (*ProxyPool, error) {
+	var entries []string
+	for _, value := range values {
+		if info, err := os.Stat(value); err == nil && !info.IsDir() {
+			lines, err := LoadWordlist(value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read proxy list: %v", err)
+			}
+			entries = append(entries, lines...)
+			continue
+		}
+		entries = append(entries, value)
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	pool := &ProxyPool{}
+	for _, entry := range entries {
+		proxyURL, err := url.Parse(entry)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy %q", entry)
+		}
+
+		switch proxyURL.Scheme {
+		case "http", "https":
+		case "socks5", "socks5h":
+			dialer, err := proxy.FromURL(proxyURL, &net.Dialer{Timeout: 10 * time.Second})
+			if err != nil {
+				return nil, fmt.Errorf("invalid proxy %q: %v", entry, err)
+			}
+			contextDialer, ok := dialer.(proxy.ContextDialer)
+			if !ok {
+				return nil, fmt.Errorf("invalid proxy %q: no context dialer", entry)
+			}
+			pool.socks = append(pool.socks, contextDialer)
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme %q (use http, https or socks5)", proxyURL.Scheme)
+		}
+		pool.proxies = append(pool.proxies, proxyURL)
+	}
+
+	return pool, nil
+}
+
+// activePool returns the loaded proxy pool, or nil
+func activePool() *ProxyPool {
+	proxiesLock.RLock()
+	defer proxiesLock.RUnlock()
+	return activeProxies
+}
+
+// Proxy selects the proxy for a request, for use as http.Transport.Proxy.
+// Proxies are rotated for every new connection. Without a proxy list the
+// standard proxy environment variables are used.
+func Proxy(req *http.Request) (*url.URL, error) {
+	pool := activePool()
+	if pool == nil {
+		return http.ProxyFromEnvironment(req)
+	}
+
+	i := atomic.AddUint32(&pool.next, 1) - 1
+	return pool.proxies[int(i)%len(pool.proxies)], nil
+}
+
+// DialContext opens a TCP connection through the next SOCKS5 proxy of the
+// loaded list, or directly when there is none
+func DialContext(ctx context.Context, network string, address string) (net.Conn, error) {
+	pool := activePool()
+	if pool == nil || len(pool.socks) == 0 {
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, network, address)
+	}
+
+	i := atomic.AddUint32(&pool.nextTCP, 1) - 1
+	return pool.socks[int(i)%len(pool.socks)].DialContext(ctx, network, address)
+}
+
+// DialTimeout is DialContext with a timeout
+func DialTimeout(network string, address string, timeout time.Duration) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return DialContext(ctx, network, address)
+}
+
+// NewTransport creates an HTTP transport using the loaded proxies.
+// insecure disables certificate verification, which also lets traffic pass
+// through intercepting proxies such as Burp.
+func NewTransport(insecure bool) *http.Transport {
+	transport := &http.Transport{Proxy: Proxy}
+	if insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return transport
+}
+
+// ProxyDescription lists the loaded proxies for display, or returns an empty
+// string when there are none
+func ProxyDescription() string {
+	pool := activePool()
+	if pool == nil {
+		return ""
+	}
+
+	names := make([]string, len(pool.proxies))
+	for i, proxyURL := range pool.proxies {
+		redacted := *proxyURL
+		if redacted.User != nil {
+			redacted.User = url.User(redacted.User.Username())
+		}
+		names[i] = redacted.String()
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	activeResolver *net.Resolver
+	resolverLock   sync.RWMutex
+)
+
+// LoadResolvers makes every DNS lookup use the given name servers in turn
+// instead of the system resolver. Each value is an address with an optional
+// port (53 by default) or a file with one address per line. Without any
+// address the system resolver is used.
+func LoadResolvers(values ...string) error {
+	var servers []string
+	for _, value := range values {
+		entries := []string{value}
+		if info, err := os.Stat(value); err == nil && !info.IsDir() {
+			lines, err := LoadWordlist(value)
+			if err != nil {
+				return fmt.Errorf("failed to read resolver list: %v", err)
+			}
+			entries = lines
+		}
+
+		for _, entry := range entries {
+			server, err := resolverAddress(entry)
+			if err != nil {
+				return err
+			}
+			servers = append(servers, server)
+		}
+	}
+	if len(servers) == 0 {
+		resolverLock.Lock()
+		activeResolver = nil
+		resolverLock.Unlock()
+		return nil
+	}
+
+	var next uint32
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			i := atomic.AddUint32(&next, 1) - 1
+			return dialer.DialContext(ctx, network, servers[int(i)%len(servers)])
+		},
+	}
+
+	resolverLock.Lock()
+	activeResolver = resolver
+	resolverLock.Unlock()
+	return nil
+}
+
+// resolverAddress validates a name server address and adds the default port
+func resolverAddress(value string) (string, error) {
+	if net.ParseIP(value) != nil {
+		return net.JoinHostPort(value, "53"), nil
+	}
+	host, _, err := net.SplitHostPort(value)
+	if err != nil || net.ParseIP(host) == nil {
+		return "", fmt.Errorf("invalid resolver %q (use an IP address with an optional port)", value)
+	}
+	return value, nil
+}
+
+// Resolver returns the resolver used for DNS lookups
+func Resolver() *net.Resolver {
+	resolverLock.RLock()
+	defer resolverLock.RUnlock()
+	if activeResolver == nil {
+		return net.DefaultResolver
+	}
+	return activeResolver
+}
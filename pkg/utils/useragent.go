@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"sort"
+	"strings"
+)
+
+// DefaultUserAgent identifies the tool in requests that set no other user agent
+const DefaultUserAgent = "Mozilla/5.0 (compatible; Sub/1.0; +https://github.com/SayerLinux/sub)"
+
+// UserAgentProfiles are named user agents that can be given instead of a
+// full user agent string
+var UserAgentProfiles = map[string]string{
+	"sub":     DefaultUserAgent,
+	"chrome":  "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36",
+	"firefox": "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:131.0) Gecko/20100101 Firefox/131.0",
+	"safari":  "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.0 Safari/605.1.15",
+	"edge":    "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36 Edg/129.0.0.0",
+	"android": "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Mobile Safari/537.36",
+	"iphone":  "Mozilla/5.0 (iPhone; CPU iPhone OS 18_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.0 Mobile/15E148 Safari/604.1",
+}
+
+// ResolveUserAgent returns the user agent of a profile name, or the value
+// itself if it is not a profile
+func ResolveUserAgent(value string) string {
+	if agent, ok := UserAgentProfiles[strings.ToLower(value)]; ok {
+		return agent
+	}
+	return value
+}
+
+// UserAgentProfileNames returns the sorted profile names
+func UserAgentProfileNames() []string {
+	names := make([]string, 0, len(UserAgentProfiles))
+	for name := range UserAgentProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BrowserUserAgents returns the user agents of the browser profiles, used
+// when rotating without an explicit list
+func BrowserUserAgents() []string {
+	var agents []string
+	for _, name := range UserAgentProfileNames() {
+		if name != "sub" {
+			agents = append(agents, UserAgentProfiles[name])
+		}
+	}
+	return agents
+}
@@ -0,0 +1,185 @@
+package utils
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// WordlistReader streams the entries of a wordlist file, plain or gzip
+// compressed, so lists larger than memory can be used. Empty lines and
+// comments are skipped.
+type WordlistReader struct {
+	source  io.ReadSeeker
+	closer  io.Closer
+	counter *countingReader
+	reader  io.Reader
+	scanner *bufio.Scanner
+	size    int64
+	offset  int64
+	word    string
+}
+
+// countingReader counts the bytes read from the wordlist file
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.count += int64(n)
+	return n, err
+}
+
+// OpenWordlist opens a wordlist for streaming. Gzip compressed lists are
+// detected from their content, whatever their name.
+func OpenWordlist(path string) (*WordlistReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open wordlist file: %v", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to open wordlist file: %v", err)
+	}
+
+	r, err := NewWordlistReader(file, info.Size())
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	r.closer = file
+	return r, nil
+}
+
+// NewWordlistReader streams a wordlist held in memory or in any other
+// seekable source of the given size
+func NewWordlistReader(source io.ReadSeeker, size int64) (*WordlistReader, error) {
+	r := &WordlistReader{source: source, size: size}
+	if err := r.reset(0); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// reset starts reading the file from the beginning, or from an offset of a
+// plain list
+func (r *WordlistReader) reset(offset int64) error {
+	if _, err := r.source.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek in wordlist: %v", err)
+	}
+	r.counter = &countingReader{reader: r.source, count: offset}
+	r.offset = offset
+
+	buffered := bufio.NewReader(r.counter)
+	r.reader = buffered
+	if offset == 0 {
+		if magic, err := buffered.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+			decompressed, err := gzip.NewReader(buffered)
+			if err != nil {
+				return fmt.Errorf("invalid gzip wordlist: %v", err)
+			}
+			r.reader = decompressed
+		}
+	}
+
+	r.scanner = bufio.NewScanner(r.reader)
+	r.scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := bufio.ScanLines(data, atEOF)
+		r.offset += int64(advance)
+		return advance, token, err
+	})
+	return nil
+}
+
+// Compressed reports whether the wordlist is gzip compressed
+func (r *WordlistReader) Compressed() bool {
+	_, ok := r.reader.(*gzip.Reader)
+	return ok
+}
+
+// Skip moves to an offset returned by Offset, to resume a previous run. It
+// must be called before the first entry is read.
+func (r *WordlistReader) Skip(offset int64) error {
+	if offset <= 0 {
+		return nil
+	}
+	if !r.Compressed() {
+		if offset > r.size {
+			return fmt.Errorf("resume offset %d is past the end of the wordlist", offset)
+		}
+		return r.reset(offset)
+	}
+
+	// Compressed lists cannot be seeked, so the skipped part is decompressed
+	for r.offset < offset && r.scanner.Scan() {
+	}
+	if r.offset < offset {
+		return fmt.Errorf("resume offset %d is past the end of the wordlist", offset)
+	}
+	return nil
+}
+
+// Scan advances to the next entry, returning false at the end of the list
+// or on an error
+func (r *WordlistReader) Scan() bool {
+	for r.scanner.Scan() {
+		word := strings.TrimSpace(r.scanner.Text())
+		// Skip empty lines and comments
+		if word != "" && !strings.HasPrefix(word, "#") {
+			r.word = word
+			return true
+		}
+	}
+	return false
+}
+
+// Word returns the current entry
+func (r *WordlistReader) Word() string {
+	return r.word
+}
+
+// Offset returns the position after the current entry in the uncompressed
+// list, which Skip accepts to resume from the next entry
+func (r *WordlistReader) Offset() int64 {
+	return r.offset
+}
+
+// Progress returns the fraction of the file read so far
+func (r *WordlistReader) Progress() float64 {
+	if r.size == 0 {
+		return 1
+	}
+	progress := float64(r.counter.count) / float64(r.size)
+	if progress > 1 {
+		progress = 1
+	}
+	return progress
+}
+
+// Size returns the size of the wordlist
+func (r *WordlistReader) Size() int64 {
+	return r.size
+}
+
+// Err returns the first error met while reading
+func (r *WordlistReader) Err() error {
+	if err := r.scanner.Err(); err != nil {
+		return fmt.Errorf("error reading wordlist file: %v", err)
+	}
+	return nil
+}
+
+// Close closes the wordlist file
+func (r *WordlistReader) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}